@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// ReportConfig is a declarative set of report jobs, loaded with -config
+type ReportConfig struct {
+	// database connection, the password still comes from the environment or password file
+	Connection ConnectionSettings `json:"connection"`
+	// load the data from a fixture file rather than the database
+	Fixture string `json:"fixture"`
+	// number of projects to load concurrently, the -workers flag when left out
+	Workers *int `json:"workers"`
+	// limit on the time for a single query (eg 5m)
+	QueryTimeout string `json:"query_timeout"`
+	// a JSON file of the edit check classification rules
+	Rules string `json:"rules"`
+	// save a snapshot of each run
+	History HistorySettings `json:"history"`
+	Jobs    []ReportJob     `json:"jobs"`
+}
+
+// ReportJob is a single report, run against the URLs matching its patterns
+type ReportJob struct {
+	Name     string   `json:"name"`
+	Patterns []string `json:"patterns"`
+	// specific Rave URLs, qualified with the domain
+	URLs []string `json:"urls"`
+	// substring, exact, glob or regex
+	Match   string   `json:"match"`
+	Exclude []string `json:"exclude"`
+	// the Rave domain, may be empty
+	Domain          string `json:"domain"`
+	ContinueOnError bool   `json:"continue_on_error"`
+	// the cohorts for the Summary Counts sheet, the standard ones when empty
+	Cohorts []Cohort `json:"cohorts"`
+	// the sheets to write, all but the optional ones when empty
+	Sheets           []string `json:"sheets"`
+	OutputDir        string   `json:"output_dir"`
+	FilenameTemplate string   `json:"filename_template"`
+	// suffix, refuse or overwrite an existing workbook
+	OnCollision string `json:"on_collision"`
+	// xlsx, csv, json, html, table, markdown or parquet
+	Format string `json:"format"`
+	// the edit check metrics to report: active, inactive or all for both
+	EditStatus string `json:"edit_status"`
+	// the number of checks in each top and bottom N of the Check Ranking sheet
+	RankingSize int `json:"ranking_size"`
+}
+
+// the job settings when nothing else is specified
+func defaultReportJob() ReportJob {
+	return ReportJob{
+		Match:            MatchSubstring.String(),
+		Domain:           "mdsol.com",
+		FilenameTemplate: "{prefix}_{date}.xlsx",
+		OnCollision:      CollisionSuffix.String(),
+		Format:           FormatXLSX.String(),
+		EditStatus:       ActiveChecks.String(),
+		RankingSize:      10,
+	}
+}
+
+// the flags setting up the command line job, which have nothing to apply to with -config
+var jobFlagNames = []string{
+	"pattern", "url", "exclude", "domain", "match", "continue", "cohort", "sheet", "output-dir",
+	"output", "format", "edit-status", "on-collision", "ranking-size",
+}
+
+// is the flag one of the job flags
+func isJobFlag(name string) bool {
+	for _, jobFlag := range jobFlagNames {
+		if jobFlag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decode JSON, rejecting any field the value doesn't have so a misspelt setting isn't ignored
+func decodeStrictJSON(data []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
+
+// UnmarshalJSON fills in the defaults for anything the job leaves out
+func (job *ReportJob) UnmarshalJSON(data []byte) error {
+	type plainJob ReportJob
+	// the thresholds of the first configuration files became the cohorts
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err == nil {
+		if _, ok := fields["thresholds"]; ok {
+			return fmt.Errorf(`"thresholds" has been replaced by "cohorts", eg ` +
+				`[{"name": "Subject Count", "where": ["subject_count > 10"]}, ` +
+				`{"name": "Completed Subjects", "where": ["completed_count > 1"]}]`)
+		}
+	}
+	decoded := plainJob(defaultReportJob())
+	if err := decodeStrictJSON(data, &decoded); err != nil {
+		return err
+	}
+	*job = ReportJob(decoded)
+	return nil
+}
+
+// load and check a configuration file, the configuration is JSON
+func loadReportConfig(fileName string) (*ReportConfig, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	config := new(ReportConfig)
+	if err := decodeStrictJSON(content, config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", fileName, err)
+	}
+	if len(config.Jobs) == 0 {
+		return nil, fmt.Errorf("%s has no jobs", fileName)
+	}
+	if _, err := config.queryTimeout(0); err != nil {
+		return nil, err
+	}
+	if config.Workers != nil && *config.Workers < 1 {
+		return nil, fmt.Errorf("workers must be at least 1, got %d", *config.Workers)
+	}
+	for idx, job := range config.Jobs {
+		if job.Name == "" {
+			config.Jobs[idx].Name = fmt.Sprintf("job%d", idx+1)
+		}
+		if len(job.Patterns) == 0 && len(job.URLs) == 0 {
+			return nil, fmt.Errorf("job %s needs patterns or urls", config.Jobs[idx].Name)
+		}
+		if _, _, err := job.matchers(); err != nil {
+			return nil, fmt.Errorf("job %s: %w", config.Jobs[idx].Name, err)
+		}
+		if _, err := job.reportOptions(1); err != nil {
+			return nil, fmt.Errorf("job %s: %w", config.Jobs[idx].Name, err)
+		}
+	}
+	return config, nil
+}
+
+// the query timeout, falling back to the supplied value
+func (config *ReportConfig) queryTimeout(fallback time.Duration) (time.Duration, error) {
+	if config.QueryTimeout == "" {
+		return fallback, nil
+	}
+	timeout, err := time.ParseDuration(config.QueryTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid query_timeout: %w", err)
+	}
+	return timeout, nil
+}
+
+// build the matchers for the patterns, urls and exclusions
+func (job ReportJob) matchers() (patterns, exclusions []*URLMatcher, err error) {
+	mode, err := parseURLMatchMode(job.Match)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, urlPattern := range job.Patterns {
+		matcher, err := newURLMatcher(mode, urlPattern)
+		if err != nil {
+			return nil, nil, err
+		}
+		patterns = append(patterns, matcher)
+	}
+	// the URLs name a host, whatever the match mode
+	domain := strings.Trim(job.Domain, ".")
+	for _, raveURL := range job.URLs {
+		// if we don't end with the domain, then set it
+		matcher, err := newURLMatcher(MatchExact, qualifyURL(raveURL, domain))
+		if err != nil {
+			return nil, nil, err
+		}
+		patterns = append(patterns, matcher)
+	}
+	for _, urlPattern := range job.Exclude {
+		matcher, err := newURLMatcher(mode, urlPattern)
+		if err != nil {
+			return nil, nil, err
+		}
+		exclusions = append(exclusions, matcher)
+	}
+	return
+}
+
+// build the options for generating the reports
+func (job ReportJob) reportOptions(workers int) (ReportOptions, error) {
+	options := ReportOptions{
+		JobName:          job.Name,
+		ContinueOnError:  job.ContinueOnError,
+		Workers:          workers,
+		Domain:           strings.Trim(job.Domain, "."),
+		Sheets:           make(map[string]bool),
+		OutputDir:        job.OutputDir,
+		FilenameTemplate: job.FilenameTemplate,
+		RankingSize:      job.RankingSize,
+	}
+	if err := checkFilenameTemplate(job.FilenameTemplate); err != nil {
+		return options, err
+	}
+	if job.RankingSize < 1 {
+		return options, fmt.Errorf("ranking_size must be at least 1")
+	}
+	onCollision, err := parseCollisionPolicy(job.OnCollision)
+	if err != nil {
+		return options, err
+	}
+	options.OnCollision = onCollision
+	format, err := parseReportFormat(job.Format)
+	if err != nil {
+		return options, err
+	}
+	options.Format = format
+	editStatus, err := parseEditStatusFilter(job.EditStatus)
+	if err != nil {
+		return options, err
+	}
+	options.EditStatus = editStatus
+	cohorts := job.Cohorts
+	if len(cohorts) == 0 {
+		cohorts = defaultCohorts()
+	}
+	now := time.Now()
+	for _, cohort := range cohorts {
+		if err := cohort.compile(now); err != nil {
+			return options, err
+		}
+		options.Cohorts = append(options.Cohorts, cohort)
+	}
+	sheets := job.Sheets
+	if len(sheets) == 0 {
+		sheets = allSheets
+	}
+	for _, sheet := range sheets {
+		if !isKnownSheet(sheet) {
+			return options, fmt.Errorf("unknown sheet %q, expected one of %s",
+				sheet, strings.Join(append(allSheets, optionalSheets...), ", "))
+		}
+		options.Sheets[sheet] = true
+	}
+	return options, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// write the configuration to a temporary file and load it
+func loadTestConfig(t *testing.T, content string) (*ReportConfig, error) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "projector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return loadReportConfig(fileName)
+}
+
+func TestLoadReportConfig(t *testing.T) {
+	config, err := loadReportConfig(filepath.Join("fixtures", "demo-config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Jobs) == 0 {
+		t.Fatal("expected the demo jobs")
+	}
+	if config.Workers == nil || *config.Workers != 2 {
+		t.Errorf("expected the 2 workers of the demo, got %v", config.Workers)
+	}
+	config, err = loadTestConfig(t, `{"jobs": [{"patterns": ["pharma"]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the -workers flag applies when the file leaves them out
+	if config.Workers != nil {
+		t.Errorf("expected no workers, got %d", *config.Workers)
+	}
+	// the defaults fill in what the job leaves out
+	job := config.Jobs[0]
+	expected := defaultReportJob()
+	if job.Name != "job1" || job.Domain != expected.Domain || job.Format != expected.Format ||
+		job.RankingSize != expected.RankingSize {
+		t.Errorf("expected the defaults for job1, got %+v", job)
+	}
+}
+
+func TestLoadReportConfigErrors(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{`{"jobs": []}`, "has no jobs"},
+		{`{"jobs": [{"name": "empty"}]}`, "needs patterns or urls"},
+		{`{"jobz": [{"patterns": ["pharma"]}]}`, `unknown field "jobz"`},
+		{`{"connection": {"hostname": "x"}, "jobs": [{"patterns": ["pharma"]}]}`, `unknown field "hostname"`},
+		{`{"jobs": [{"patterns": ["pharma"], "sheetz": ["versions"]}]}`, `unknown field "sheetz"`},
+		{`{"jobs": [{"patterns": ["pharma"], "cohorts": [{"name": "x", "when": []}]}]}`, `unknown field "when"`},
+		{`{"jobs": [{"patterns": ["pharma"], "thresholds": {"subject_count": 10}}]}`, `replaced by "cohorts"`},
+		{`{"jobs": [{"patterns": ["pharma"], "format": "pdf"}]}`, "pdf"},
+		{`{"query_timeout": "soon", "jobs": [{"patterns": ["pharma"]}]}`, "query_timeout"},
+		{`{"workers": 0, "jobs": [{"patterns": ["pharma"]}]}`, "workers must be at least 1"},
+		{`{"workers": -2, "jobs": [{"patterns": ["pharma"]}]}`, "workers must be at least 1"},
+		{"jobs:\n  - patterns: [pharma]\n", "parsing"},
+	}
+	for _, test := range tests {
+		_, err := loadTestConfig(t, test.content)
+		if err == nil {
+			t.Errorf("%s: expected an error", test.content)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.content, test.expected, err)
+		}
+	}
+}
+
+func TestIsJobFlag(t *testing.T) {
+	for _, name := range []string{"pattern", "url", "format", "sheet", "ranking-size"} {
+		if !isJobFlag(name) {
+			t.Errorf("expected -%s to be a job flag", name)
+		}
+	}
+	for _, name := range []string{"config", "workers", "fixture", "dbhost", "rules"} {
+		if isJobFlag(name) {
+			t.Errorf("expected -%s not to be a job flag", name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"sort"
+)
+
+// RankedCheck is an edit check of a last version, with its rows combined
+type RankedCheck struct {
+	ProjectName   string
+	EditCheckName string
+	FormOIDs      string
+	FieldOIDs     string
+	Executions    int
+	ChangeCount   int
+	NoChangeCount int
+}
+
+// the no change count relative to the executions, false when the check never executed
+func (check RankedCheck) noChangeRatio() (float64, bool) {
+	if check.Executions <= 0 {
+		return 0, false
+	}
+	return float64(check.NoChangeCount) / float64(check.Executions), true
+}
+
+// the changes per execution, false when the check never executed
+func (check RankedCheck) changeYield() (float64, bool) {
+	if check.Executions <= 0 {
+		return 0, false
+	}
+	return float64(check.ChangeCount) / float64(check.Executions), true
+}
+
+// a way of ordering the checks, the highest first
+type checkRankingMeasure struct {
+	Name  string
+	value func(check RankedCheck) (float64, bool)
+}
+
+var checkRankingMeasures = []checkRankingMeasure{
+	{"Queries Fired", func(check RankedCheck) (float64, bool) { return float64(check.Executions), true }},
+	{"No Change Ratio", RankedCheck.noChangeRatio},
+	{"Change Yield", RankedCheck.changeYield},
+}
+
+// CheckRank is a check in a top or bottom N
+type CheckRank struct {
+	// the project name, or All Projects
+	Scope   string
+	Measure string
+	// Top or Bottom
+	Position string
+	Rank     int
+	Check    RankedCheck
+}
+
+// the checks of the last version of a project, combined by name, for the edit status
+func rankedChecksForProject(project *Project, status EditStatusFilter) []RankedCheck {
+	lastVersion := project.getLastVersion()
+	if lastVersion == nil {
+		return nil
+	}
+	var selected []*EditCheck
+	for _, check := range lastVersion.EditChecks {
+		if status.includes(check.IsActive) {
+			selected = append(selected, check)
+		}
+	}
+	definitions := editCheckDefinitions(selected)
+	counts := make(map[string]*RankedCheck)
+	var names []string
+	for _, check := range selected {
+		ranked, ok := counts[check.EditCheckName]
+		if !ok {
+			definition := definitions[check.EditCheckName]
+			ranked = &RankedCheck{
+				ProjectName:   project.ProjectName,
+				EditCheckName: check.EditCheckName,
+				FormOIDs:      definition.FormOIDs,
+				FieldOIDs:     definition.FieldOIDs,
+			}
+			counts[check.EditCheckName] = ranked
+			names = append(names, check.EditCheckName)
+		}
+		ranked.Executions += check.TotalCheckExecutions
+		ranked.ChangeCount += check.ChangeCount
+		ranked.NoChangeCount += check.NoChangeCount
+	}
+	sort.Strings(names)
+	checks := make([]RankedCheck, 0, len(names))
+	for _, name := range names {
+		checks = append(checks, *counts[name])
+	}
+	return checks
+}
+
+// the top and bottom N of the checks for each measure, a check is only listed once per measure
+func rankChecks(scope string, checks []RankedCheck, size int) []CheckRank {
+	var ranks []CheckRank
+	for _, measure := range checkRankingMeasures {
+		type valuedCheck struct {
+			check RankedCheck
+			value float64
+		}
+		var valued []valuedCheck
+		for _, check := range checks {
+			if value, ok := measure.value(check); ok {
+				valued = append(valued, valuedCheck{check, value})
+			}
+		}
+		// highest first, then by project and name
+		sort.SliceStable(valued, func(i, j int) bool {
+			if valued[i].value != valued[j].value {
+				return valued[i].value > valued[j].value
+			}
+			if valued[i].check.ProjectName != valued[j].check.ProjectName {
+				return valued[i].check.ProjectName < valued[j].check.ProjectName
+			}
+			return valued[i].check.EditCheckName < valued[j].check.EditCheckName
+		})
+		top := size
+		if top > len(valued) {
+			top = len(valued)
+		}
+		for idx := 0; idx < top; idx++ {
+			ranks = append(ranks, CheckRank{scope, measure.Name, "Top", idx + 1, valued[idx].check})
+		}
+		// the bottom N, lowest first, leaving out those in the top N
+		for idx := len(valued) - 1; idx >= top && idx >= len(valued)-size; idx-- {
+			ranks = append(ranks, CheckRank{scope, measure.Name, "Bottom", len(valued) - idx, valued[idx].check})
+		}
+	}
+	return ranks
+}
+
+// rank the checks of the last versions for each project and across all of them
+func rankProjectChecks(projects []*Project, status EditStatusFilter, size int) []CheckRank {
+	var ranks []CheckRank
+	var portfolio []RankedCheck
+	for _, project := range projects {
+		checks := rankedChecksForProject(project, status)
+		ranks = append(ranks, rankChecks(project.ProjectName, checks, size)...)
+		portfolio = append(portfolio, checks...)
+	}
+	return append(ranks, rankChecks("All Projects", portfolio, size)...)
+}
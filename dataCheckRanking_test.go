@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testRankedCheck(name string, executions, changes, noChanges int) RankedCheck {
+	return RankedCheck{
+		ProjectName:   "Alpha",
+		EditCheckName: name,
+		Executions:    executions,
+		ChangeCount:   changes,
+		NoChangeCount: noChanges,
+	}
+}
+
+// the ranks for a measure as position, rank and check name
+func rankNames(ranks []CheckRank, measure string) []string {
+	var names []string
+	for _, rank := range ranks {
+		if rank.Measure == measure {
+			names = append(names, fmt.Sprintf("%s %d %s", rank.Position, rank.Rank, rank.Check.EditCheckName))
+		}
+	}
+	return names
+}
+
+func TestRankChecks(t *testing.T) {
+	checks := []RankedCheck{
+		testRankedCheck("A", 50, 5, 45),
+		testRankedCheck("B", 40, 30, 10),
+		testRankedCheck("C", 30, 3, 3),
+		testRankedCheck("D", 20, 20, 0),
+		// never executed, so left out of the ratios
+		testRankedCheck("E", 0, 0, 0),
+	}
+	ranks := rankChecks("Alpha", checks, 2)
+	tests := []struct {
+		measure  string
+		expected []string
+	}{
+		{"Queries Fired", []string{"Top 1 A", "Top 2 B", "Bottom 1 E", "Bottom 2 D"}},
+		{"No Change Ratio", []string{"Top 1 A", "Top 2 B", "Bottom 1 D", "Bottom 2 C"}},
+		{"Change Yield", []string{"Top 1 D", "Top 2 B", "Bottom 1 C", "Bottom 2 A"}},
+	}
+	for _, test := range tests {
+		if got := rankNames(ranks, test.measure); !equalStrings(got, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.measure, test.expected, got)
+		}
+	}
+	for _, rank := range ranks {
+		if rank.Scope != "Alpha" {
+			t.Errorf("expected the Alpha scope, got %s", rank.Scope)
+		}
+	}
+}
+
+func TestRankChecksListsEachCheckOnce(t *testing.T) {
+	checks := []RankedCheck{
+		testRankedCheck("A", 30, 0, 0),
+		testRankedCheck("B", 20, 0, 0),
+		testRankedCheck("C", 10, 0, 0),
+	}
+	// the bottom 2 would overlap the top 2
+	expected := []string{"Top 1 A", "Top 2 B", "Bottom 1 C"}
+	if got := rankNames(rankChecks("Alpha", checks, 2), "Queries Fired"); !equalStrings(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := rankChecks("Alpha", nil, 2); len(got) != 0 {
+		t.Errorf("expected no ranks without checks, got %v", got)
+	}
+}
+
+func TestRankChecksTieBreak(t *testing.T) {
+	checks := []RankedCheck{
+		testRankedCheck("B", 10, 0, 0),
+		testRankedCheck("A", 10, 0, 0),
+	}
+	expected := []string{"Top 1 A", "Bottom 1 B"}
+	if got := rankNames(rankChecks("Alpha", checks, 1), "Queries Fired"); !equalStrings(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestRankedChecksForProject(t *testing.T) {
+	project := &Project{ProjectName: "Alpha", Versions: []*ProjectVersion{
+		{CRFVersionID: 1, EditChecks: []*EditCheck{
+			{EditCheckName: "OLD", IsActive: 1, TotalCheckExecutions: 99},
+		}},
+		{CRFVersionID: 2, LastVersion: true, EditChecks: []*EditCheck{
+			{EditCheckName: "DM_AGE", FormOID: "DM", FieldOID: "AGE", IsActive: 1, TotalCheckExecutions: 4, ChangeCount: 1, NoChangeCount: 3},
+			{EditCheckName: "DM_AGE", FormOID: "DM", FieldOID: "BRTHDAT", IsActive: 1, TotalCheckExecutions: 6, ChangeCount: 2, NoChangeCount: 4},
+			{EditCheckName: "AE_OFF", FormOID: "AE", FieldOID: "AETERM", IsActive: 0, TotalCheckExecutions: 2},
+		}},
+	}}
+	tests := []struct {
+		status   EditStatusFilter
+		expected []RankedCheck
+	}{
+		{ActiveChecks, []RankedCheck{
+			{"Alpha", "DM_AGE", "DM", "AGE|BRTHDAT", 10, 3, 7},
+		}},
+		{InactiveChecks, []RankedCheck{
+			{"Alpha", "AE_OFF", "AE", "AETERM", 2, 0, 0},
+		}},
+		{AllChecks, []RankedCheck{
+			{"Alpha", "AE_OFF", "AE", "AETERM", 2, 0, 0},
+			{"Alpha", "DM_AGE", "DM", "AGE|BRTHDAT", 10, 3, 7},
+		}},
+	}
+	for _, test := range tests {
+		got := rankedChecksForProject(project, test.status)
+		if len(got) != len(test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.status, test.expected, got)
+			continue
+		}
+		for idx, check := range got {
+			if check != test.expected[idx] {
+				t.Errorf("%s: expected %+v, got %+v", test.status, test.expected[idx], check)
+			}
+		}
+	}
+}
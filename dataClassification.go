@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ClassificationRule matches edit checks by their name and actions, a check has to
+// match everything the rule sets
+type ClassificationRule struct {
+	// the flag the rule sets, unused for the field rules
+	Category string `json:"category"`
+	// a glob (* and ?) on the edit check name, eg SYS_NC_*
+	Name string `json:"name"`
+	// text the actions contain, eg CustomFunction
+	Actions string `json:"actions"`
+	name    *regexp.Regexp
+}
+
+// ClassificationRules decide the type of each edit check and the flags shown for the
+// unused edits
+type ClassificationRules struct {
+	// the rules for the field checks, the rest are programmed
+	Field []ClassificationRule `json:"field"`
+	// the flags for the unused edits, a column per category
+	Flags []ClassificationRule `json:"flags"`
+}
+
+// the rules when there is no rules file, matching the standard Rave naming
+func defaultClassificationRules() *ClassificationRules {
+	rules := &ClassificationRules{
+		Field: defaultFieldRules(),
+		Flags: defaultFlagRules(),
+	}
+	// the defaults always compile
+	_ = rules.compile()
+	return rules
+}
+
+// SYS followed by anything, as with LIKE 'SYS_%'
+func defaultFieldRules() []ClassificationRule {
+	return []ClassificationRule{{Name: "SYS?*"}}
+}
+
+func defaultFlagRules() []ClassificationRule {
+	return []ClassificationRule{
+		{Category: "Non-conformance", Name: "SYS_NC_*"},
+		{Category: "Required", Name: "SYS_REQ_*"},
+		{Category: "Future", Name: "SYS_FUTURE_*"},
+		{Category: "Range", Name: "SYS_Q_RANGE_*"},
+	}
+}
+
+// load a rules file, a section it leaves out takes the default rules
+func loadClassificationRules(fileName string) (*ClassificationRules, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	rules := new(ClassificationRules)
+	if err := decodeStrictJSON(content, rules); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", fileName, err)
+	}
+	if rules.Field == nil {
+		rules.Field = defaultFieldRules()
+	}
+	if rules.Flags == nil {
+		rules.Flags = defaultFlagRules()
+	}
+	if err := rules.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return rules, nil
+}
+
+// check the rules and compile the name patterns
+func (rules *ClassificationRules) compile() error {
+	for idx := range rules.Field {
+		if err := rules.Field[idx].compile(); err != nil {
+			return err
+		}
+	}
+	for idx := range rules.Flags {
+		if rules.Flags[idx].Category == "" {
+			return fmt.Errorf("flag rule %d has no category", idx+1)
+		}
+		if err := rules.Flags[idx].compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rule *ClassificationRule) compile() error {
+	if rule.Name == "" && rule.Actions == "" {
+		return fmt.Errorf("a rule needs a name or actions")
+	}
+	if rule.Name != "" {
+		expr, err := regexp.Compile(globToRegexp(rule.Name))
+		if err != nil {
+			return fmt.Errorf("invalid name %q: %w", rule.Name, err)
+		}
+		rule.name = expr
+	}
+	return nil
+}
+
+// does the edit check match the rule
+func (rule *ClassificationRule) matches(editCheckName, actions string) bool {
+	if rule.name != nil && !rule.name.MatchString(editCheckName) {
+		return false
+	}
+	return rule.Actions == "" || strings.Contains(actions, rule.Actions)
+}
+
+// add a query argument, returning its placeholder
+func addQueryArg(args *[]interface{}, value interface{}) string {
+	*args = append(*args, value)
+	return "$" + strconv.Itoa(len(*args))
+}
+
+// the SQL condition on an edit_check row for the rule
+func (rule *ClassificationRule) sqlCondition(args *[]interface{}) string {
+	var conditions []string
+	if rule.Name != "" {
+		conditions = append(conditions, `edit_check_name LIKE `+addQueryArg(args, globToLike(rule.Name))+` ESCAPE '\'`)
+	}
+	if rule.Actions != "" {
+		conditions = append(conditions, `actions LIKE '%' || `+addQueryArg(args, escapeLike(rule.Actions))+` || '%' ESCAPE '\'`)
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")"
+}
+
+// is the edit check a field check
+func (rules *ClassificationRules) isField(editCheckName, actions string) bool {
+	for idx := range rules.Field {
+		if rules.Field[idx].matches(editCheckName, actions) {
+			return true
+		}
+	}
+	return false
+}
+
+// the check type (0 for field, 1 for programmed) of an edit_check row in SQL
+func (rules *ClassificationRules) checkTypeSQL(args *[]interface{}) string {
+	if len(rules.Field) == 0 {
+		return "1"
+	}
+	var conditions []string
+	for idx := range rules.Field {
+		conditions = append(conditions, rules.Field[idx].sqlCondition(args))
+	}
+	return "CASE WHEN " + strings.Join(conditions, " OR ") + " THEN 0 ELSE 1 END"
+}
+
+// the flag categories, in the order they first appear
+func (rules *ClassificationRules) flagCategories() []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, rule := range rules.Flags {
+		if !seen[rule.Category] {
+			seen[rule.Category] = true
+			categories = append(categories, rule.Category)
+		}
+	}
+	return categories
+}
+
+// does any rule for the category match the edit check
+func (rules *ClassificationRules) hasFlag(category, editCheckName, actions string) bool {
+	for idx := range rules.Flags {
+		if rules.Flags[idx].Category == category && rules.Flags[idx].matches(editCheckName, actions) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tealeg/xlsx"
+)
+
+func loadTestRules(t *testing.T, content string) (*ClassificationRules, error) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "projector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "rules.json")
+	if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return loadClassificationRules(fileName)
+}
+
+func TestDefaultClassificationRules(t *testing.T) {
+	rules := defaultClassificationRules()
+	tests := []struct {
+		editCheckName string
+		field         bool
+		flags         []string
+	}{
+		{"SYS_NC_AE_AESTDAT", true, []string{"Non-conformance"}},
+		{"SYS_FUTURE_VS_VSDAT", true, []string{"Future"}},
+		{"SYS_Q_RANGE_VS_TEMP", true, []string{"Range"}},
+		{"SYSREQ", true, nil},
+		// SYS alone doesn't match LIKE 'SYS_%'
+		{"SYS", false, nil},
+		{"CM_DUPLICATE_MED", false, nil},
+	}
+	if categories := rules.flagCategories(); !equalStrings(categories, []string{"Non-conformance", "Required", "Future", "Range"}) {
+		t.Errorf("unexpected flag categories %v", categories)
+	}
+	for _, test := range tests {
+		if got := rules.isField(test.editCheckName, ""); got != test.field {
+			t.Errorf("%s: expected field %v, got %v", test.editCheckName, test.field, got)
+		}
+		var flags []string
+		for _, category := range rules.flagCategories() {
+			if rules.hasFlag(category, test.editCheckName, "") {
+				flags = append(flags, category)
+			}
+		}
+		if !equalStrings(flags, test.flags) {
+			t.Errorf("%s: expected flags %v, got %v", test.editCheckName, test.flags, flags)
+		}
+	}
+}
+
+func TestLoadClassificationRules(t *testing.T) {
+	rules, err := loadTestRules(t, `{
+  "flags": [
+    {"category": "Custom", "actions": "CustomFunction"},
+    {"category": "Derived", "name": "*_CALC", "actions": "SetDataPoint"},
+    {"category": "Custom", "name": "CF_*"}
+  ]
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the field rules were left out, so keep the defaults
+	if !rules.isField("SYS_REQ_DM", "OpenQuery") || rules.isField("DM_AGE_CALC", "") {
+		t.Error("expected the default field rules")
+	}
+	if categories := rules.flagCategories(); !equalStrings(categories, []string{"Custom", "Derived"}) {
+		t.Errorf("expected the Custom and Derived categories, got %v", categories)
+	}
+	tests := []struct {
+		category      string
+		editCheckName string
+		actions       string
+		expected      bool
+	}{
+		{"Custom", "DM_CHECK", "OpenQuery|CustomFunction", true},
+		{"Custom", "CF_DM", "OpenQuery", true},
+		{"Custom", "DM_CHECK", "OpenQuery", false},
+		{"Derived", "DM_AGE_CALC", "SetDataPoint", true},
+		// a rule with a name and actions needs both
+		{"Derived", "DM_AGE_CALC", "OpenQuery", false},
+		{"Derived", "DM_AGE", "SetDataPoint", false},
+	}
+	for _, test := range tests {
+		if got := rules.hasFlag(test.category, test.editCheckName, test.actions); got != test.expected {
+			t.Errorf("%s %s %s: expected %v, got %v", test.category, test.editCheckName, test.actions, test.expected, got)
+		}
+	}
+}
+
+func TestLoadClassificationRulesEmptySection(t *testing.T) {
+	rules, err := loadTestRules(t, `{"field": [], "flags": []}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.isField("SYS_REQ_DM", "") || len(rules.flagCategories()) != 0 {
+		t.Error("expected an empty list to have no rules")
+	}
+	var args []interface{}
+	if got := rules.checkTypeSQL(&args); got != "1" || len(args) != 0 {
+		t.Errorf("expected every check to be programmed, got %q %v", got, args)
+	}
+}
+
+func TestLoadClassificationRulesErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		message string
+	}{
+		{`{"flags": [{"name": "SYS_NC_*"}]}`, "has no category"},
+		{`{"field": [{}]}`, "needs a name or actions"},
+		{`{"field": [{"name": "SYS_*", "action": "OpenQuery"}]}`, "unknown field"},
+		{`{"fields": []}`, "unknown field"},
+	}
+	for _, test := range tests {
+		_, err := loadTestRules(t, test.content)
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.content, test.message, err)
+		}
+	}
+}
+
+func TestCheckTypeSQL(t *testing.T) {
+	rules := &ClassificationRules{Field: []ClassificationRule{
+		{Name: "SYS?*"},
+		{Name: "FLD_*", Actions: "50%"},
+	}}
+	if err := rules.compile(); err != nil {
+		t.Fatal(err)
+	}
+	var args []interface{}
+	expected := `CASE WHEN (edit_check_name LIKE $1 ESCAPE '\') OR ` +
+		`(edit_check_name LIKE $2 ESCAPE '\' AND actions LIKE '%' || $3 || '%' ESCAPE '\') THEN 0 ELSE 1 END`
+	if got := rules.checkTypeSQL(&args); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+	expectedArgs := []string{`SYS_%`, `FLD\_%`, `50\%`}
+	if len(args) != len(expectedArgs) {
+		t.Fatalf("expected the arguments %v, got %v", expectedArgs, args)
+	}
+	for idx, arg := range args {
+		if arg != expectedArgs[idx] {
+			t.Errorf("argument %d: expected %q, got %q", idx+1, expectedArgs[idx], arg)
+		}
+	}
+}
+
+func TestUnusedEditActionsArePerProject(t *testing.T) {
+	store := &MemoryStore{
+		EditChecks: []fixtureEditCheck{
+			{ProjectID: 1, CRFVersionID: 1, EditCheckName: "DM_CHECK", FormOID: "DM", Actions: "OpenQuery"},
+			{ProjectID: 2, CRFVersionID: 2, EditCheckName: "DM_CHECK", FormOID: "DEMOG", Actions: "OpenQuery|CustomFunction"},
+		},
+		rules: defaultClassificationRules(),
+	}
+	edits, err := store.getUselessEditsForProject(context.Background(), 1, OpenQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 {
+		t.Fatalf("expected one unused edit, got %d", len(edits))
+	}
+	if edits[0].Actions != "OpenQuery" || edits[0].CustomFunction {
+		t.Errorf("expected only the actions of the project, got %q (custom function %v)", edits[0].Actions, edits[0].CustomFunction)
+	}
+	// the OIDs are across every project with the check, as the query has always done
+	if edits[0].FormOID != "DEMOG|DM" {
+		t.Errorf("expected the form OIDs of both projects, got %q", edits[0].FormOID)
+	}
+}
+
+func TestWriteUselessEditsFlagColumns(t *testing.T) {
+	custom, err := loadTestRules(t, `{"flags": [{"category": "Custom", "actions": "CustomFunction"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rules       *ClassificationRules
+		headers     int
+		bottomRight string
+		flags       []string
+	}{
+		// the four default flags after the seven fixed columns
+		{defaultClassificationRules(), 11, "K1", []string{"Y", "N", "N", "N"}},
+		{custom, 8, "H1", []string{"Y"}},
+	}
+	edits := []*UnusedEdit{{EditCheckName: "SYS_NC_DM_AGE", Actions: "OpenQuery|CustomFunction", CustomFunction: true}}
+	for _, test := range tests {
+		workbook := xlsx.NewFile()
+		if err := writeUselessEdits("Alpha", edits, OpenQuery, test.rules, workbook); err != nil {
+			t.Fatal(err)
+		}
+		rows := sheetRows(t, workbook, "Unused Edits w OpenQuery")
+		if len(rows[0]) != test.headers {
+			t.Errorf("expected %d headers, got %v", test.headers, rows[0])
+		}
+		filter := workbook.Sheet["Unused Edits w OpenQuery"].AutoFilter
+		if filter.TopLeftCell != "A1" || filter.BottomRightCell != test.bottomRight {
+			t.Errorf("expected the filter A1:%s, got %s:%s", test.bottomRight, filter.TopLeftCell, filter.BottomRightCell)
+		}
+		if got := rows[1][7:]; !equalStrings(got, test.flags) {
+			t.Errorf("expected the flags %v, got %v", test.flags, got)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cohort is a named group of projects for the Summary Counts sheet, a project
+// belongs to the cohort when it satisfies all of the conditions
+type Cohort struct {
+	Name string `json:"name"`
+	// conditions such as "subject_count > 10" or "refresh_date >= 90d"
+	Where      []string `json:"where"`
+	conditions []cohortCondition
+}
+
+// a single comparison against a SubjectCount field
+type cohortCondition struct {
+	Field    string
+	Operator string
+	// the number, or the unix time for the refresh date
+	Value int64
+}
+
+// the fields of SubjectCount a cohort can compare
+var cohortFields = map[string]func(SubjectCount) sql.NullInt64{
+	"subject_count": func(sc SubjectCount) sql.NullInt64 {
+		return sql.NullInt64{Int64: int64(sc.SubjectCount), Valid: true}
+	},
+	"screening_count":         func(sc SubjectCount) sql.NullInt64 { return sc.ScreeningCount },
+	"screening_failure_count": func(sc SubjectCount) sql.NullInt64 { return sc.ScreeningFailureCount },
+	"enrolled_count":          func(sc SubjectCount) sql.NullInt64 { return sc.EnrolledCount },
+	"early_terminated_count":  func(sc SubjectCount) sql.NullInt64 { return sc.EarlyTerminatedCount },
+	"completed_count":         func(sc SubjectCount) sql.NullInt64 { return sc.CompletedCount },
+	"follow_up_count":         func(sc SubjectCount) sql.NullInt64 { return sc.FollowUpCount },
+	"refresh_date": func(sc SubjectCount) sql.NullInt64 {
+		return sql.NullInt64{Int64: sc.RefreshDate.Time.Unix(), Valid: sc.RefreshDate.Valid}
+	},
+}
+
+// longest first, so >= isn't read as >
+var cohortOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// the cohorts used when none are configured
+func defaultCohorts() []Cohort {
+	return []Cohort{
+		{Name: "All Projects"},
+		{Name: "Subject Count", Where: []string{"subject_count > 10"}},
+		{Name: "Completed Subjects", Where: []string{"completed_count > 1"}},
+	}
+}
+
+// parse the value for a condition, the refresh date takes a date (2006-01-02) or a
+// number of days before now (90d)
+func parseCohortValue(field, value string, now time.Time) (int64, error) {
+	if field != "refresh_date" {
+		return strconv.ParseInt(value, 10, 64)
+	}
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return now.AddDate(0, 0, -days).Unix(), nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return 0, err
+	}
+	return date.Unix(), nil
+}
+
+// parse a condition such as "enrolled_count >= 20"
+func parseCohortCondition(text string, now time.Time) (cohortCondition, error) {
+	for _, operator := range cohortOperators {
+		idx := strings.Index(text, operator)
+		if idx < 0 {
+			continue
+		}
+		condition := cohortCondition{
+			Field:    strings.TrimSpace(text[:idx]),
+			Operator: operator,
+		}
+		if _, ok := cohortFields[condition.Field]; !ok {
+			return condition, fmt.Errorf("unknown cohort field %q in %q", condition.Field, text)
+		}
+		value, err := parseCohortValue(condition.Field, strings.TrimSpace(text[idx+len(operator):]), now)
+		if err != nil {
+			return condition, fmt.Errorf("invalid value in %q: %w", text, err)
+		}
+		condition.Value = value
+		return condition, nil
+	}
+	return cohortCondition{}, fmt.Errorf("no comparison in cohort condition %q", text)
+}
+
+// parse a cohort from the command line, eg "Large:subject_count > 100,completed_count >= 1"
+func parseCohort(text string) Cohort {
+	parts := strings.SplitN(text, ":", 2)
+	cohort := Cohort{Name: strings.TrimSpace(parts[0])}
+	if len(parts) == 2 {
+		for _, condition := range strings.Split(parts[1], ",") {
+			if condition = strings.TrimSpace(condition); condition != "" {
+				cohort.Where = append(cohort.Where, condition)
+			}
+		}
+	}
+	return cohort
+}
+
+// parse the conditions for the cohort
+func (c *Cohort) compile(now time.Time) error {
+	if c.Name == "" {
+		return fmt.Errorf("cohort needs a name")
+	}
+	c.conditions = nil
+	for _, text := range c.Where {
+		condition, err := parseCohortCondition(text, now)
+		if err != nil {
+			return fmt.Errorf("cohort %s: %w", c.Name, err)
+		}
+		c.conditions = append(c.conditions, condition)
+	}
+	return nil
+}
+
+// does the condition hold, a missing value never matches
+func (cc cohortCondition) matches(counts SubjectCount) bool {
+	value := cohortFields[cc.Field](counts)
+	if !value.Valid {
+		return false
+	}
+	switch cc.Operator {
+	case ">":
+		return value.Int64 > cc.Value
+	case ">=":
+		return value.Int64 >= cc.Value
+	case "<":
+		return value.Int64 < cc.Value
+	case "<=":
+		return value.Int64 <= cc.Value
+	case "!=":
+		return value.Int64 != cc.Value
+	default:
+		return value.Int64 == cc.Value
+	}
+}
+
+// does the project belong in the cohort
+func (c *Cohort) includes(project *Project) bool {
+	for _, condition := range c.conditions {
+		if !condition.matches(project.SubjectCount) {
+			return false
+		}
+	}
+	return true
+}
+
+// describe the conditions for the Threshold column
+func (c *Cohort) criteria() string {
+	if len(c.Where) == 0 {
+		return "ALL"
+	}
+	return strings.Join(c.Where, " and ")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestParseCohort(t *testing.T) {
+	tests := []struct {
+		text     string
+		name     string
+		criteria []string
+	}{
+		{"All Projects", "All Projects", nil},
+		{"Large:subject_count > 100", "Large", []string{"subject_count > 100"}},
+		{" Large : subject_count > 100, completed_count >= 1 ,", "Large", []string{"subject_count > 100", "completed_count >= 1"}},
+		{"Recent:refresh_date >= 2020-01-31", "Recent", []string{"refresh_date >= 2020-01-31"}},
+	}
+	for _, test := range tests {
+		cohort := parseCohort(test.text)
+		if cohort.Name != test.name || !equalStrings(cohort.Where, test.criteria) {
+			t.Errorf("parseCohort(%q): expected %q %q, got %q %q", test.text, test.name, test.criteria, cohort.Name, cohort.Where)
+		}
+	}
+}
+
+func TestParseCohortCondition(t *testing.T) {
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		text     string
+		expected cohortCondition
+	}{
+		{"subject_count > 10", cohortCondition{"subject_count", ">", 10}},
+		{"subject_count>=10", cohortCondition{"subject_count", ">=", 10}},
+		{"completed_count <= 1", cohortCondition{"completed_count", "<=", 1}},
+		{"enrolled_count != 0", cohortCondition{"enrolled_count", "!=", 0}},
+		{"follow_up_count = 3", cohortCondition{"follow_up_count", "=", 3}},
+		{"screening_count < 5", cohortCondition{"screening_count", "<", 5}},
+		{"refresh_date >= 2020-01-31", cohortCondition{"refresh_date", ">=",
+			time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC).Unix()}},
+		{"refresh_date >= 30d", cohortCondition{"refresh_date", ">=", now.AddDate(0, 0, -30).Unix()}},
+	}
+	for _, test := range tests {
+		got, err := parseCohortCondition(test.text, now)
+		if err != nil {
+			t.Errorf("parseCohortCondition(%q): %v", test.text, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("parseCohortCondition(%q): expected %+v, got %+v", test.text, test.expected, got)
+		}
+	}
+}
+
+func TestParseCohortConditionErrors(t *testing.T) {
+	now := time.Now()
+	for _, text := range []string{
+		"subject_count",
+		"subjects > 10",
+		"subject_count > ten",
+		"refresh_date >= yesterday",
+		"refresh_date >= xd",
+		"refresh_date >= 2020-31-01",
+	} {
+		if _, err := parseCohortCondition(text, now); err == nil {
+			t.Errorf("parseCohortCondition(%q): expected an error", text)
+		}
+	}
+}
+
+func TestCohortIncludes(t *testing.T) {
+	now := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	project := &Project{SubjectCount: SubjectCount{
+		SubjectCount:   42,
+		CompletedCount: sql.NullInt64{Int64: 12, Valid: true},
+		RefreshDate:    pq.NullTime{Time: time.Date(2020, 2, 20, 0, 0, 0, 0, time.UTC), Valid: true},
+	}}
+	tests := []struct {
+		where    []string
+		expected bool
+	}{
+		{nil, true},
+		{[]string{"subject_count > 10"}, true},
+		{[]string{"subject_count > 10", "completed_count > 20"}, false},
+		{[]string{"refresh_date >= 30d"}, true},
+		{[]string{"refresh_date >= 5d"}, false},
+		// a missing value never matches
+		{[]string{"enrolled_count >= 0"}, false},
+		{[]string{"enrolled_count != 1"}, false},
+	}
+	for _, test := range tests {
+		cohort := Cohort{Name: "test", Where: test.where}
+		if err := cohort.compile(now); err != nil {
+			t.Fatal(err)
+		}
+		if got := cohort.includes(project); got != test.expected {
+			t.Errorf("%q: expected %v, got %v", test.where, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EditCheck is an edit_check row for a CRF version
+type EditCheck struct {
+	ProjectID            int    `db:"project_id"`
+	CRFVersionID         int    `db:"crf_version_id"`
+	EditCheckName        string `db:"edit_check_name"`
+	FormOID              string `db:"form_oid"`
+	FieldOID             string `db:"field_oid"`
+	VariableOID          string `db:"variable_oid"`
+	Actions              string `db:"actions"`
+	IsActive             int    `db:"is_active"`
+	TotalCheckExecutions int    `db:"total_check_executions"`
+	OpenChecks           int    `db:"open_checks"`
+	ChangeCount          int    `db:"change_count"`
+	NoChangeCount        int    `db:"no_change_count"`
+}
+
+// the changes per execution, false when the check never executed
+func (check *EditCheck) yield() (float64, bool) {
+	if check.TotalCheckExecutions <= 0 {
+		return 0, false
+	}
+	return float64(check.ChangeCount) / float64(check.TotalCheckExecutions), true
+}
+
+// load the edit checks for every version of the projects in a URL
+func loadEditChecks(ctx context.Context, store Store, urlID int, projects []*Project) error {
+	editChecks, err := store.getEditChecksForURL(ctx, urlID)
+	if err != nil {
+		return fmt.Errorf("loading edit checks: %w", err)
+	}
+	versions := make(map[[2]int]*ProjectVersion)
+	for _, project := range projects {
+		for _, version := range project.Versions {
+			versions[[2]int{version.ProjectID, version.CRFVersionID}] = version
+		}
+	}
+	for idx := range editChecks {
+		version, ok := versions[[2]int{editChecks[idx].ProjectID, editChecks[idx].CRFVersionID}]
+		if !ok {
+			continue
+		}
+		version.EditChecks = append(version.EditChecks, &editChecks[idx])
+	}
+	return nil
+}
+
+// ProjectEditCheckName is the name of an edit check in any version of a project
+type ProjectEditCheckName struct {
+	ProjectID     int    `db:"project_id"`
+	EditCheckName string `db:"edit_check_name"`
+}
+
+// load the names of the edit checks of the projects in a URL, so a later run can tell
+// a check that fired from one that was removed
+func loadEditCheckNames(ctx context.Context, store Store, urlID int, projects []*Project) error {
+	names, err := store.getEditCheckNamesForURL(ctx, urlID)
+	if err != nil {
+		return fmt.Errorf("loading edit check names: %w", err)
+	}
+	byID := make(map[int]*Project)
+	for _, project := range projects {
+		project.EditCheckNames = []string{}
+		byID[project.ProjectID] = project
+	}
+	for _, name := range names {
+		if project, ok := byID[name.ProjectID]; ok {
+			project.EditCheckNames = append(project.EditCheckNames, name.EditCheckName)
+		}
+	}
+	return nil
+}
+
+// the edit check rows with the same name in a version, as a single check
+type editCheckDefinition struct {
+	Name         string
+	Active       bool
+	FormOIDs     string
+	FieldOIDs    string
+	VariableOIDs string
+	Actions      string
+}
+
+// the distinct, non-empty values, pipe separated
+func joinDistinct(values []string) string {
+	seen := make(map[string]bool)
+	var distinct []string
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		distinct = append(distinct, value)
+	}
+	sort.Strings(distinct)
+	return strings.Join(distinct, "|")
+}
+
+// combine the rows of a version by edit check name, active if any row is
+func editCheckDefinitions(checks []*EditCheck) map[string]editCheckDefinition {
+	grouped := make(map[string][]*EditCheck)
+	for _, check := range checks {
+		grouped[check.EditCheckName] = append(grouped[check.EditCheckName], check)
+	}
+	definitions := make(map[string]editCheckDefinition)
+	for name, rows := range grouped {
+		definition := editCheckDefinition{Name: name}
+		var formOIDs, fieldOIDs, variableOIDs, actions []string
+		for _, row := range rows {
+			definition.Active = definition.Active || row.IsActive == 1
+			formOIDs = append(formOIDs, row.FormOID)
+			fieldOIDs = append(fieldOIDs, row.FieldOID)
+			variableOIDs = append(variableOIDs, row.VariableOID)
+			actions = append(actions, row.Actions)
+		}
+		definition.FormOIDs = joinDistinct(formOIDs)
+		definition.FieldOIDs = joinDistinct(fieldOIDs)
+		definition.VariableOIDs = joinDistinct(variableOIDs)
+		definition.Actions = joinDistinct(actions)
+		definitions[name] = definition
+	}
+	return definitions
+}
@@ -0,0 +1,8 @@
+package main
+
+// LoadFailure records a Project that was skipped, and why
+type LoadFailure struct {
+	URL         string
+	ProjectName string
+	Err         error
+}
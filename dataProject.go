@@ -1,7 +1,7 @@
 package main
 
 import (
-	"github.com/jmoiron/sqlx"
+	"context"
 	"sort"
 )
 
@@ -14,20 +14,30 @@ type Project struct {
 	Versions            []*ProjectVersion
 	UnusedWithOpenQuery []*UnusedEdit
 	Unused              []*UnusedEdit
+	// the names of the edit checks in any version, nil when they weren't loaded
+	EditCheckNames []string
 }
 
 // load the subject count for a Project
-func (pv *Project) loadSubjectCount(db *sqlx.DB, urlID int) {
-	pv.SubjectCount = getProjectSubjectCount(db, urlID, pv.ProjectID)
+func (pv *Project) loadSubjectCount(ctx context.Context, store Store, urlID int) (err error) {
+	pv.SubjectCount, err = store.getProjectSubjectCount(ctx, urlID, pv.ProjectID)
+	return
 }
 
 // load the useless edits
-func (pj *Project) loadUnusedQueries(db *sqlx.DB) {
+func (pj *Project) loadUnusedQueries(ctx context.Context, store Store) error {
 	// with OpenQuery
-	openQuery := getUselessEditsForProject(db, pj.ProjectID, OpenQuery)
-	noOpenQuery := getUselessEditsForProject(db, pj.ProjectID, WithoutOpenQuery)
+	openQuery, err := store.getUselessEditsForProject(ctx, pj.ProjectID, OpenQuery)
+	if err != nil {
+		return err
+	}
+	noOpenQuery, err := store.getUselessEditsForProject(ctx, pj.ProjectID, WithoutOpenQuery)
+	if err != nil {
+		return err
+	}
 	pj.Unused = noOpenQuery
 	pj.UnusedWithOpenQuery = openQuery
+	return nil
 }
 
 // retrieve a project Version by CRF Version
@@ -1,7 +1,8 @@
 package main
 
 import (
-	"github.com/jmoiron/sqlx"
+	"context"
+	"fmt"
 	"sort"
 )
 
@@ -10,6 +11,13 @@ type EditStatusCounts struct {
 	InactiveEdits int `db:"inactive_count"`
 }
 
+// EditStatusCounts for a single ProjectVersion, as bulk loaded for a URL
+type VersionStatusCounts struct {
+	ProjectID    int `db:"project_id"`
+	CRFVersionID int `db:"crf_version_id"`
+	EditStatusCounts
+}
+
 // ProjectVersion represents the structure for an individual Project Version
 type ProjectVersion struct {
 	ProjectID          int  `db:"project_id"`
@@ -18,34 +26,92 @@ type ProjectVersion struct {
 	EditStatus         EditStatusCounts
 	FieldEditMetrics   EditTypeMetric
 	ProgramEditMetrics EditTypeMetric
-	ActiveCheckCount   int
-	InActiveCheckCount int
+	// the same metrics over the inactive edits
+	InactiveFieldEditMetrics   EditTypeMetric
+	InactiveProgramEditMetrics EditTypeMetric
+	ActiveCheckCount           int
+	InActiveCheckCount         int
+	// the edit_check rows, only loaded for the sheets that need them
+	EditChecks []*EditCheck
+}
+
+// the field and programmed metrics over the active or inactive edits
+func (pv *ProjectVersion) editMetrics(status EditStatusFilter) (field, programmed *EditTypeMetric) {
+	if status == InactiveChecks {
+		return &pv.InactiveFieldEditMetrics, &pv.InactiveProgramEditMetrics
+	}
+	return &pv.FieldEditMetrics, &pv.ProgramEditMetrics
 }
 
-// Total count of edits
-func (pv *ProjectVersion) getTotalEdits() int {
-	return pv.FieldEditMetrics.TotalEdits + pv.ProgramEditMetrics.TotalEdits
+// set the loaded metrics for a check type and status
+func (pv *ProjectVersion) setEditMetrics(checkType EditCheckClass, isActive int, metric EditTypeMetric) {
+	status := ActiveChecks
+	if isActive == 0 {
+		status = InactiveChecks
+	}
+	field, programmed := pv.editMetrics(status)
+	if checkType == Field {
+		*field = metric
+	} else {
+		*programmed = metric
+	}
 }
 
-// load the check counts
-func (pv *ProjectVersion) getActivityCounts(db *sqlx.DB) {
-	pv.EditStatus = getActivityCount(db, pv.ProjectID, pv.CRFVersionID)
+// calculate the metrics from the loaded raw values
+func (pv *ProjectVersion) calculateMetrics() {
+	for _, metrics := range []*EditTypeMetric{
+		&pv.FieldEditMetrics,
+		&pv.ProgramEditMetrics,
+		&pv.InactiveFieldEditMetrics,
+		&pv.InactiveProgramEditMetrics,
+	} {
+		// impute the raw values
+		metrics.fixUpMetrics()
+		// calculate the percentages
+		metrics.calculatePercentages()
+	}
 }
 
-// load the metrics
-func (pv *ProjectVersion) getMetrics(db *sqlx.DB) {
-	// field edits
-	fieldEdits := getStudyMetricsByProjectAndCheckType(db, pv.ProjectID, pv.CRFVersionID, Field)
-	programmedEdits := getStudyMetricsByProjectAndCheckType(db, pv.ProjectID, pv.CRFVersionID, Programmed)
-	// impute the raw values
-	fieldEdits.fixUpMetrics()
-	programmedEdits.fixUpMetrics()
-	// calculate the percentages
-	fieldEdits.calculatePercentages()
-	programmedEdits.calculatePercentages()
-	// set the values
-	pv.FieldEditMetrics = fieldEdits
-	pv.ProgramEditMetrics = programmedEdits
+// load the metrics over the active and inactive edit checks and the check counts for
+// every version of the projects in a URL
+func loadVersionMetrics(ctx context.Context, store Store, urlID int, projects []*Project) error {
+	metrics, err := store.getVersionMetricsForURL(ctx, urlID)
+	if err != nil {
+		return fmt.Errorf("loading metrics: %w", err)
+	}
+	statusCounts, err := store.getActivityCountsForURL(ctx, urlID)
+	if err != nil {
+		return fmt.Errorf("loading activity counts: %w", err)
+	}
+	// index the versions
+	type versionKey struct {
+		projectID    int
+		crfVersionID int
+	}
+	versions := make(map[versionKey]*ProjectVersion)
+	for _, project := range projects {
+		for _, version := range project.Versions {
+			versions[versionKey{version.ProjectID, version.CRFVersionID}] = version
+		}
+	}
+	for _, metric := range metrics {
+		version, ok := versions[versionKey{metric.ProjectID, metric.CRFVersionID}]
+		if !ok {
+			continue
+		}
+		version.setEditMetrics(metric.CheckType, metric.IsActive, metric.EditTypeMetric)
+	}
+	for _, counts := range statusCounts {
+		version, ok := versions[versionKey{counts.ProjectID, counts.CRFVersionID}]
+		if !ok {
+			continue
+		}
+		version.EditStatus = counts.EditStatusCounts
+	}
+	for _, version := range versions {
+		version.calculateMetrics()
+	}
+	return nil
 }
 
 // Sorter
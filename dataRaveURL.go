@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -10,6 +11,10 @@ type RaveURL struct {
 	URLID        int    `db:"id"`
 	AlternateURL string `db:"alternate_url"`
 	Projects     []*Project
+	// the Rave domain (eg mdsol.com), empty if the URLs have no common domain
+	Domain string
+	// the pattern the URL was selected by
+	MatchedPattern string
 }
 
 // Get the URL by looking across the two candidates
@@ -22,7 +27,24 @@ func (r *RaveURL) URL() string {
 
 // Get the Prefix URL (eg pharma.mdsol.com => pharma)
 func (r *RaveURL) URLPrefix() string {
-	return strings.Split(r.URL(), ".")[0]
+	url := r.URL()
+	// strip the domain
+	if r.Domain != "" && strings.HasSuffix(url, "."+r.Domain) {
+		return strings.TrimSuffix(url, "."+r.Domain)
+	}
+	// otherwise fall back to the host name, or the whole URL if there are no dots
+	if prefix := strings.Split(url, ".")[0]; prefix != "" {
+		return prefix
+	}
+	return fmt.Sprintf("url_%d", r.URLID)
+}
+
+// add the domain to a URL that lacks it (eg pharma => pharma.mdsol.com)
+func qualifyURL(url, domain string) string {
+	if domain == "" || strings.HasSuffix(url, "."+domain) {
+		return url
+	}
+	return url + "." + domain
 }
 
 //func createRaveURL(r Record) *RaveURL {
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// RunSide describes one of the runs being compared
+type RunSide struct {
+	// the JSON export or stored run it was loaded from
+	Source      string
+	GeneratedAt time.Time
+	Job         string
+}
+
+// ValueDelta is a metric in the two runs, nil when it wasn't recorded
+type ValueDelta struct {
+	Old *int64
+	New *int64
+	// 1 if an increase is an improvement, -1 if a decrease is, 0 for neither
+	Direction int
+}
+
+// the change between the runs, false when either is missing
+func (delta ValueDelta) change() (int64, bool) {
+	if delta.Old == nil || delta.New == nil {
+		return 0, false
+	}
+	return *delta.New - *delta.Old, true
+}
+
+// did the value get worse between the runs
+func (delta ValueDelta) regression() bool {
+	change, ok := delta.change()
+	return ok && change*int64(delta.Direction) < 0
+}
+
+// ProjectDelta is the change in the metrics for a project
+type ProjectDelta struct {
+	ProjectName string
+	// Added, Removed or empty when the project is in both runs
+	Status string
+	// in the order of projectDeltaMetrics
+	Values []ValueDelta
+}
+
+// EditDelta is an edit check that was unused in only one of the runs
+type EditDelta struct {
+	ProjectName string
+	JSONUnusedEdit
+	OpenQuery bool
+	// the edit was unused in the old run but not the new, and is still a check of the project
+	NowUsed bool
+	// the edit was unused in the old run and is no longer a check of the project
+	Removed bool
+}
+
+// RunDelta is the comparison of two runs for a Rave URL
+type RunDelta struct {
+	URL      string
+	Old      RunSide
+	New      RunSide
+	Projects []ProjectDelta
+	Edits    []EditDelta
+}
+
+// a metric compared for each project
+type projectDeltaMetric struct {
+	Name      string
+	Direction int
+	value     func(project JSONProject) *int64
+}
+
+// add the values, nil when both are
+func sumNullable(values ...*int64) *int64 {
+	var total *int64
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		if total == nil {
+			total = new(int64)
+		}
+		*total += *value
+	}
+	return total
+}
+
+// the field and programmed metrics of the version over the active or inactive edits
+func (version JSONProjectVersion) editMetrics(status EditStatusFilter) (field, programmed JSONEditMetrics) {
+	if status == InactiveChecks {
+		return version.InactiveFieldEdits, version.InactiveProgrammedEdits
+	}
+	return version.FieldEdits, version.ProgrammedEdits
+}
+
+// the metric over the field and programmed edits of the last version
+func lastVersionMetric(status EditStatusFilter, metric func(JSONEditMetrics) *int64) func(JSONProject) *int64 {
+	return func(project JSONProject) *int64 {
+		for _, version := range project.Versions {
+			if version.LastVersion {
+				field, programmed := version.editMetrics(status)
+				return sumNullable(metric(field), metric(programmed))
+			}
+		}
+		return nil
+	}
+}
+
+var projectDeltaMetrics = []projectDeltaMetric{
+	{"Subject Count", 0, func(project JSONProject) *int64 {
+		count := int64(project.SubjectCounts.SubjectCount)
+		return &count
+	}},
+	{"Enrolled Count", 0, func(project JSONProject) *int64 { return project.SubjectCounts.EnrolledCount }},
+	{"Completed Count", 0, func(project JSONProject) *int64 { return project.SubjectCounts.CompletedCount }},
+	{"Edits Fired", 1, lastVersionMetric(ActiveChecks, func(metrics JSONEditMetrics) *int64 { return metrics.TotalFiredWithOpenQuery })},
+	{"Edits Unfired", -1, lastVersionMetric(ActiveChecks, func(metrics JSONEditMetrics) *int64 { return metrics.TotalNotFiredWithOpenQuery })},
+	{"Queries", 0, lastVersionMetric(ActiveChecks, func(metrics JSONEditMetrics) *int64 { return metrics.TotalQueries })},
+	{"Open Queries", -1, lastVersionMetric(ActiveChecks, func(metrics JSONEditMetrics) *int64 { return metrics.TotalOpenQueries })},
+	{"Inactive Edits", 0, lastVersionMetric(InactiveChecks, func(metrics JSONEditMetrics) *int64 { return metrics.TotalEdits })},
+	{"Inactive Queries", 0, lastVersionMetric(InactiveChecks, func(metrics JSONEditMetrics) *int64 { return metrics.TotalQueries })},
+	{"Inactive Open Queries", -1, lastVersionMetric(InactiveChecks, func(metrics JSONEditMetrics) *int64 { return metrics.TotalOpenQueries })},
+}
+
+// compare the metrics for a project, either may be nil when the project is only in one run
+func newProjectDelta(name string, oldProject, newProject *JSONProject) ProjectDelta {
+	delta := ProjectDelta{ProjectName: name}
+	switch {
+	case oldProject == nil:
+		delta.Status = "Added"
+	case newProject == nil:
+		delta.Status = "Removed"
+	}
+	for _, metric := range projectDeltaMetrics {
+		value := ValueDelta{Direction: metric.Direction}
+		if oldProject != nil {
+			value.Old = metric.value(*oldProject)
+		}
+		if newProject != nil {
+			value.New = metric.value(*newProject)
+		}
+		delta.Values = append(delta.Values, value)
+	}
+	return delta
+}
+
+// the unused edits only in the first list
+func unusedEditsMissingFrom(edits, others []JSONUnusedEdit) []JSONUnusedEdit {
+	names := make(map[string]bool)
+	for _, edit := range others {
+		names[edit.EditCheckName] = true
+	}
+	var missing []JSONUnusedEdit
+	for _, edit := range edits {
+		if !names[edit.EditCheckName] {
+			missing = append(missing, edit)
+		}
+	}
+	return missing
+}
+
+// is the edit check in any version of the project, assumed to be when the run didn't
+// record the names
+func (project JSONProject) hasEditCheck(name string) bool {
+	if project.EditCheckNames == nil {
+		return true
+	}
+	for _, editCheckName := range project.EditCheckNames {
+		if editCheckName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// the edits that became used, newly unused or were removed in a project
+func newEditDeltas(oldProject, newProject JSONProject) []EditDelta {
+	var deltas []EditDelta
+	for _, openQuery := range []bool{true, false} {
+		oldEdits, newEdits := oldProject.UnusedEditsWithoutOpenQuery, newProject.UnusedEditsWithoutOpenQuery
+		if openQuery {
+			oldEdits, newEdits = oldProject.UnusedEditsWithOpenQuery, newProject.UnusedEditsWithOpenQuery
+		}
+		for _, edit := range unusedEditsMissingFrom(newEdits, oldEdits) {
+			deltas = append(deltas, EditDelta{ProjectName: newProject.ProjectName, JSONUnusedEdit: edit, OpenQuery: openQuery})
+		}
+		for _, edit := range unusedEditsMissingFrom(oldEdits, newEdits) {
+			delta := EditDelta{ProjectName: newProject.ProjectName, JSONUnusedEdit: edit, OpenQuery: openQuery}
+			if newProject.hasEditCheck(edit.EditCheckName) {
+				delta.NowUsed = true
+			} else {
+				delta.Removed = true
+			}
+			deltas = append(deltas, delta)
+		}
+	}
+	return deltas
+}
+
+// compare two runs, the projects are matched by name
+func newRunDelta(oldReport, newReport JSONReport, oldSide, newSide RunSide) RunDelta {
+	delta := RunDelta{URL: newReport.RaveURL.URL, Old: oldSide, New: newSide}
+	oldProjects := make(map[string]*JSONProject)
+	newProjects := make(map[string]*JSONProject)
+	var names []string
+	for idx := range oldReport.RaveURL.Projects {
+		project := &oldReport.RaveURL.Projects[idx]
+		oldProjects[project.ProjectName] = project
+		names = append(names, project.ProjectName)
+	}
+	for idx := range newReport.RaveURL.Projects {
+		project := &newReport.RaveURL.Projects[idx]
+		newProjects[project.ProjectName] = project
+		if oldProjects[project.ProjectName] == nil {
+			names = append(names, project.ProjectName)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		oldProject, newProject := oldProjects[name], newProjects[name]
+		delta.Projects = append(delta.Projects, newProjectDelta(name, oldProject, newProject))
+		// the edits of an added or removed project are all new or gone
+		if oldProject != nil && newProject != nil {
+			delta.Edits = append(delta.Edits, newEditDeltas(*oldProject, *newProject)...)
+		}
+	}
+	return delta
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"testing"
+)
+
+func int64Pointer(value int64) *int64 {
+	return &value
+}
+
+// a project with the open query metrics of its last version
+func testDeltaProject(name string, fired, unfired, openQueries int64, unused []string, checks []string) JSONProject {
+	project := JSONProject{
+		ProjectName:   name,
+		SubjectCounts: JSONSubjectCounts{SubjectCount: 10},
+		Versions: []JSONProjectVersion{{
+			CRFVersionID: 1,
+			LastVersion:  true,
+			FieldEdits: JSONEditMetrics{
+				TotalFiredWithOpenQuery:    int64Pointer(fired),
+				TotalNotFiredWithOpenQuery: int64Pointer(unfired),
+				TotalOpenQueries:           int64Pointer(openQueries),
+			},
+		}},
+		EditCheckNames: checks,
+	}
+	for _, name := range unused {
+		project.UnusedEditsWithOpenQuery = append(project.UnusedEditsWithOpenQuery, JSONUnusedEdit{EditCheckName: name})
+	}
+	return project
+}
+
+func testDeltaReport(projects ...JSONProject) JSONReport {
+	return JSONReport{RaveURL: JSONRaveURL{URL: "pharma.mdsol.com", Projects: projects}}
+}
+
+// the index of the metric in projectDeltaMetrics
+func deltaMetricIndex(t *testing.T, name string) int {
+	t.Helper()
+	for idx, metric := range projectDeltaMetrics {
+		if metric.Name == name {
+			return idx
+		}
+	}
+	t.Fatalf("no %s metric", name)
+	return -1
+}
+
+func TestValueDelta(t *testing.T) {
+	tests := []struct {
+		delta      ValueDelta
+		change     int64
+		ok         bool
+		regression bool
+	}{
+		{ValueDelta{int64Pointer(5), int64Pointer(8), 1}, 3, true, false},
+		{ValueDelta{int64Pointer(5), int64Pointer(2), 1}, -3, true, true},
+		{ValueDelta{int64Pointer(5), int64Pointer(8), -1}, 3, true, true},
+		{ValueDelta{int64Pointer(5), int64Pointer(8), 0}, 3, true, false},
+		{ValueDelta{nil, int64Pointer(8), -1}, 0, false, false},
+		{ValueDelta{int64Pointer(5), nil, -1}, 0, false, false},
+	}
+	for idx, test := range tests {
+		change, ok := test.delta.change()
+		if change != test.change || ok != test.ok {
+			t.Errorf("%d: expected a change of %d (%v), got %d (%v)", idx, test.change, test.ok, change, ok)
+		}
+		if got := test.delta.regression(); got != test.regression {
+			t.Errorf("%d: expected regression %v, got %v", idx, test.regression, got)
+		}
+	}
+}
+
+func TestNewRunDeltaProjects(t *testing.T) {
+	oldReport := testDeltaReport(
+		testDeltaProject("Alpha", 4, 2, 3, nil, nil),
+		testDeltaProject("Gone", 1, 1, 1, nil, nil),
+	)
+	newReport := testDeltaReport(
+		testDeltaProject("Alpha", 3, 3, 1, nil, nil),
+		testDeltaProject("Beta", 1, 0, 0, nil, nil),
+	)
+	delta := newRunDelta(oldReport, newReport, RunSide{Source: "old.json"}, RunSide{Source: "new.json"})
+	if delta.URL != "pharma.mdsol.com" || delta.Old.Source != "old.json" || delta.New.Source != "new.json" {
+		t.Errorf("unexpected runs %+v", delta)
+	}
+	var names, statuses []string
+	for _, project := range delta.Projects {
+		names = append(names, project.ProjectName)
+		statuses = append(statuses, project.Status)
+	}
+	if !equalStrings(names, []string{"Alpha", "Beta", "Gone"}) || !equalStrings(statuses, []string{"", "Added", "Removed"}) {
+		t.Fatalf("expected Alpha, Beta (Added) and Gone (Removed), got %v %v", names, statuses)
+	}
+	alpha := delta.Projects[0]
+	tests := []struct {
+		metric     string
+		change     int64
+		regression bool
+	}{
+		{"Subject Count", 0, false},
+		{"Edits Fired", -1, true},
+		{"Edits Unfired", 1, true},
+		{"Open Queries", -2, false},
+	}
+	for _, test := range tests {
+		value := alpha.Values[deltaMetricIndex(t, test.metric)]
+		change, ok := value.change()
+		if !ok || change != test.change {
+			t.Errorf("%s: expected a change of %d, got %d (%v)", test.metric, test.change, change, ok)
+		}
+		if value.regression() != test.regression {
+			t.Errorf("%s: expected regression %v", test.metric, test.regression)
+		}
+	}
+	// an added project has no old values
+	if _, ok := delta.Projects[1].Values[deltaMetricIndex(t, "Edits Fired")].change(); ok {
+		t.Error("expected no change for an added project")
+	}
+}
+
+func TestNewRunDeltaEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		checks   []string
+		expected map[string]string
+	}{
+		{"names recorded", []string{"KEPT", "NEW_UNUSED", "USED"}, map[string]string{
+			"NEW_UNUSED": "Newly unused", "USED": "Now used", "DELETED": "Removed"}},
+		// without the names a missing check can't be told from one that fired
+		{"names not recorded", nil, map[string]string{
+			"NEW_UNUSED": "Newly unused", "USED": "Now used", "DELETED": "Now used"}},
+	}
+	for _, test := range tests {
+		oldReport := testDeltaReport(testDeltaProject("Alpha", 0, 0, 0, []string{"KEPT", "USED", "DELETED"}, nil))
+		newReport := testDeltaReport(testDeltaProject("Alpha", 0, 0, 0, []string{"KEPT", "NEW_UNUSED"}, test.checks))
+		delta := newRunDelta(oldReport, newReport, RunSide{}, RunSide{})
+		got := make(map[string]string)
+		for _, edit := range delta.Edits {
+			change := "Newly unused"
+			switch {
+			case edit.NowUsed && edit.Removed:
+				change = "both"
+			case edit.NowUsed:
+				change = "Now used"
+			case edit.Removed:
+				change = "Removed"
+			}
+			got[edit.EditCheckName] = change
+		}
+		if len(got) != len(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, got)
+			continue
+		}
+		for name, change := range test.expected {
+			if got[name] != change {
+				t.Errorf("%s: expected %s to be %s, got %q", test.name, name, change, got[name])
+			}
+		}
+	}
+}
+
+func TestNewRunDeltaIgnoresEditsOfAddedProjects(t *testing.T) {
+	oldReport := testDeltaReport()
+	newReport := testDeltaReport(testDeltaProject("Beta", 0, 0, 0, []string{"UNUSED"}, []string{"UNUSED"}))
+	if delta := newRunDelta(oldReport, newReport, RunSide{}, RunSide{}); len(delta.Edits) != 0 {
+		t.Errorf("expected no edit changes for an added project, got %v", delta.Edits)
+	}
+}
+
+func TestNewRunDeltaInactiveMetrics(t *testing.T) {
+	oldProject := testDeltaProject("Alpha", 0, 0, 0, nil, nil)
+	oldProject.Versions[0].InactiveProgrammedEdits = JSONEditMetrics{
+		TotalQueries:     int64Pointer(7),
+		TotalOpenQueries: int64Pointer(3),
+	}
+	newProject := testDeltaProject("Alpha", 0, 0, 0, nil, nil)
+	newProject.Versions[0].InactiveFieldEdits = JSONEditMetrics{TotalOpenQueries: int64Pointer(1)}
+	newProject.Versions[0].InactiveProgrammedEdits = JSONEditMetrics{
+		TotalQueries:     int64Pointer(7),
+		TotalOpenQueries: int64Pointer(3),
+	}
+	delta := newRunDelta(testDeltaReport(oldProject), testDeltaReport(newProject), RunSide{}, RunSide{})
+	values := delta.Projects[0].Values
+	if change, ok := values[deltaMetricIndex(t, "Inactive Queries")].change(); !ok || change != 0 {
+		t.Errorf("expected no change in the inactive queries, got %d (%v)", change, ok)
+	}
+	openQueries := values[deltaMetricIndex(t, "Inactive Open Queries")]
+	if change, ok := openQueries.change(); !ok || change != 1 || !openQueries.regression() {
+		t.Errorf("expected a regression of 1 inactive open query, got %d (%v)", change, ok)
+	}
+	// a version without inactive edits has nothing to compare
+	if _, ok := values[deltaMetricIndex(t, "Inactive Edits")].change(); ok {
+		t.Error("expected no change in the inactive edits")
+	}
+}
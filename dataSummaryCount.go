@@ -1,17 +1,33 @@
 package main
 
-// Aggregate Count
-type AggregateCount struct {
-	ConditionDescription string
-	AllProjects          SummaryCounts
-	GreaterThanTen       SummaryCounts
-	CompletedSubjects    SummaryCounts
+// CohortCounts are the summary counts for the projects in a cohort
+type CohortCounts struct {
+	Name     string
+	Criteria string
+	Counts   SummaryCounts
+}
+
+// sum the last version metrics over the active or inactive edits of the projects in each cohort
+func summarizeCohorts(projects []*Project, cohorts []Cohort, status EditStatusFilter) []CohortCounts {
+	// Count holders
+	cohortCounts := make([]CohortCounts, len(cohorts))
+	for idx := range cohorts {
+		cohortCounts[idx].Name = cohorts[idx].Name
+		cohortCounts[idx].Criteria = cohorts[idx].criteria()
+	}
+	// Scan the projects
+	for _, project := range projects {
+		for idx := range cohorts {
+			if cohorts[idx].includes(project) {
+				cohortCounts[idx].Counts.addProject(project, status)
+			}
+		}
+	}
+	return cohortCounts
 }
 
 // SummaryCounts represents the Structure for the computed stats
 type SummaryCounts struct {
-	// what criteria are we applying
-	Threshold                         int
 	RecordCount                       int
 	SubjectCount                      int
 	TotalEdits                        int
@@ -32,6 +48,36 @@ type SummaryCounts struct {
 	TotalPrgWithNoChange              int
 }
 
+// a negative count wasn't recorded, eg a version with no edits of the type, and adds nothing
+func recordedCount(value int) int {
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
+// add the metrics for the last version of a project
+func (sc *SummaryCounts) addProject(project *Project, status EditStatusFilter) {
+	lastProjectVersion := project.getLastVersion()
+	fieldMetrics, programMetrics := lastProjectVersion.editMetrics(status)
+	sc.RecordCount++
+	sc.SubjectCount += project.SubjectCount.SubjectCount
+	sc.TotalEdits += recordedCount(fieldMetrics.TotalEdits) + recordedCount(programMetrics.TotalEdits)
+	sc.TotalFldEdits += recordedCount(fieldMetrics.TotalEdits)
+	sc.TotalFldEditsFired += recordedCount(fieldMetrics.TotalFiredWithOpenQuery)
+	sc.TotalFldEditsUnfired += recordedCount(fieldMetrics.TotalNotFiredWithOpenQuery)
+	sc.TotalFldEditsOpen += recordedCount(fieldMetrics.TotalOpenQueries)
+	sc.TotalFldWithChange += recordedCount(fieldMetrics.TotalEditsFiredWithChange)
+	sc.TotalFldWithNoChange += recordedCount(fieldMetrics.TotalEditsFiredWithNoChange)
+	sc.TotalPrgEdits += recordedCount(programMetrics.TotalEdits)
+	sc.TotalPrgEditsWithOpenQuery += recordedCount(programMetrics.TotalEditsWithOpenQuery)
+	sc.TotalPrgEditsFired += recordedCount(programMetrics.TotalFiredWithOpenQuery)
+	sc.TotalPrgEditsUnfired += recordedCount(programMetrics.TotalNotFiredWithOpenQuery)
+	sc.TotalPrgEditsOpen += recordedCount(programMetrics.TotalOpenQueries)
+	sc.TotalPrgWithChange += recordedCount(programMetrics.TotalEditsFiredWithChange)
+	sc.TotalPrgWithNoChange += recordedCount(programMetrics.TotalEditsFiredWithNoChange)
+}
+
 type AverageSummaryCounts struct {
 	RecordCount                int
 	SubjectCount               float64
@@ -1,6 +1,10 @@
 package main
 
-import "database/sql"
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
 
 // Field or Programmed Edit check
 type EditCheckClass int
@@ -18,6 +22,60 @@ const (
 	WithoutOpenQuery
 )
 
+// Which edit checks the metrics cover
+type EditStatusFilter int
+
+const (
+	ActiveChecks EditStatusFilter = iota
+	InactiveChecks
+	AllChecks
+)
+
+var editStatusFilterNames = []string{"active", "inactive", "all"}
+
+func (filter EditStatusFilter) String() string {
+	return editStatusFilterNames[filter]
+}
+
+// parse the name of an edit status filter
+func parseEditStatusFilter(name string) (EditStatusFilter, error) {
+	for idx, filterName := range editStatusFilterNames {
+		if strings.EqualFold(name, filterName) {
+			return EditStatusFilter(idx), nil
+		}
+	}
+	return ActiveChecks, fmt.Errorf("unknown edit status %q, expected one of %s",
+		name, strings.Join(editStatusFilterNames, ", "))
+}
+
+// the metric sets the filter reports, all is the active and inactive side by side
+func (filter EditStatusFilter) statuses() []EditStatusFilter {
+	if filter == AllChecks {
+		return []EditStatusFilter{ActiveChecks, InactiveChecks}
+	}
+	return []EditStatusFilter{filter}
+}
+
+// the label added to the names of the sheets for the inactive checks
+func (filter EditStatusFilter) sheetSuffix() string {
+	if filter == InactiveChecks {
+		return " (inactive)"
+	}
+	return ""
+}
+
+// does the filter include an edit check with the is_active value
+func (filter EditStatusFilter) includes(isActive int) bool {
+	switch filter {
+	case ActiveChecks:
+		return isActive == 1
+	case InactiveChecks:
+		return isActive == 0
+	default:
+		return isActive == 1 || isActive == 0
+	}
+}
+
 // EditMetric (per ProjectVersion, per EditCheckClass)
 type EditTypeMetric struct {
 	// All the edits
@@ -71,6 +129,16 @@ type EditTypeMetric struct {
 	PercentageNotChanged            float64
 }
 
+// EditTypeMetric for a single ProjectVersion and EditCheckClass, as bulk loaded for a URL
+type VersionEditTypeMetric struct {
+	ProjectID    int            `db:"project_id"`
+	CRFVersionID int            `db:"crf_version_id"`
+	CheckType    EditCheckClass `db:"check_type"`
+	// 1 for the active checks, 0 for the inactive
+	IsActive int `db:"is_active"`
+	EditTypeMetric
+}
+
 func (mtx *EditTypeMetric) fixUpMetrics() {
 	if mtx.RawTotalEditsWithOpenQuery.Valid {
 		mtx.TotalEditsWithOpenQuery = int(mtx.RawTotalEditsWithOpenQuery.Int64)
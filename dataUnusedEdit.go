@@ -9,7 +9,8 @@ type UnusedEdit struct {
 	FormOID        string `db:"form_oids"`
 	FieldOID       string `db:"field_oids"`
 	VariableOID    string `db:"variable_oids"`
+	Actions        string `db:"actions"`
 	UsageCount     int    `db:"total_count"`
 	OpenQuery      string `db:"open_query"`
-	CustomFunction bool
+	CustomFunction bool   `db:"custom_function"`
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// VersionCheckChange is an edit check that differs between consecutive CRF versions
+type VersionCheckChange struct {
+	FromVersion   int
+	ToVersion     int
+	EditCheckName string
+	// Added, Removed, Activated, Deactivated and/or Modified
+	Changes []string
+	// nil when the check isn't in the version
+	Old *editCheckDefinition
+	New *editCheckDefinition
+}
+
+// compare the definitions of the checks in two versions
+func diffEditChecks(fromVersion, toVersion int, oldChecks, newChecks map[string]editCheckDefinition) []VersionCheckChange {
+	var names []string
+	for name := range oldChecks {
+		names = append(names, name)
+	}
+	for name := range newChecks {
+		if _, ok := oldChecks[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var changes []VersionCheckChange
+	for _, name := range names {
+		change := VersionCheckChange{FromVersion: fromVersion, ToVersion: toVersion, EditCheckName: name}
+		if oldCheck, ok := oldChecks[name]; ok {
+			change.Old = &oldCheck
+		}
+		if newCheck, ok := newChecks[name]; ok {
+			change.New = &newCheck
+		}
+		switch {
+		case change.Old == nil:
+			change.Changes = append(change.Changes, "Added")
+		case change.New == nil:
+			change.Changes = append(change.Changes, "Removed")
+		default:
+			if !change.Old.Active && change.New.Active {
+				change.Changes = append(change.Changes, "Activated")
+			}
+			if change.Old.Active && !change.New.Active {
+				change.Changes = append(change.Changes, "Deactivated")
+			}
+			if change.Old.FormOIDs != change.New.FormOIDs ||
+				change.Old.FieldOIDs != change.New.FieldOIDs ||
+				change.Old.VariableOIDs != change.New.VariableOIDs ||
+				change.Old.Actions != change.New.Actions {
+				change.Changes = append(change.Changes, "Modified")
+			}
+		}
+		if len(change.Changes) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// the changes in the edit checks between each pair of consecutive versions of a project
+func (pj *Project) getVersionChanges() []VersionCheckChange {
+	versions := orderVersions(pj.Versions)
+	var changes []VersionCheckChange
+	for idx := 1; idx < len(versions); idx++ {
+		changes = append(changes, diffEditChecks(versions[idx-1].CRFVersionID, versions[idx].CRFVersionID,
+			editCheckDefinitions(versions[idx-1].EditChecks), editCheckDefinitions(versions[idx].EditChecks))...)
+	}
+	return changes
+}
+
+// the changes as a single description, eg "Deactivated, Modified"
+func (change VersionCheckChange) description() string {
+	return strings.Join(change.Changes, ", ")
+}
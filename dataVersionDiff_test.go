@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestDiffEditChecks(t *testing.T) {
+	oldChecks := editCheckDefinitions([]*EditCheck{
+		{EditCheckName: "REMOVED", FormOID: "DM", IsActive: 1},
+		{EditCheckName: "ACTIVATED", FormOID: "DM", IsActive: 0},
+		{EditCheckName: "DEACTIVATED", FormOID: "DM", IsActive: 1},
+		{EditCheckName: "MOVED", FormOID: "DM", FieldOID: "AGE", IsActive: 1},
+		{EditCheckName: "ACTIONS", FormOID: "DM", Actions: "OpenQuery", IsActive: 1},
+		{EditCheckName: "BOTH", FormOID: "DM", IsActive: 1},
+		{EditCheckName: "SAME", FormOID: "DM", FieldOID: "AGE", IsActive: 1},
+		{EditCheckName: "SAME", FormOID: "DM", FieldOID: "BRTHDAT", IsActive: 0},
+	})
+	newChecks := editCheckDefinitions([]*EditCheck{
+		{EditCheckName: "ADDED", FormOID: "DM", IsActive: 1},
+		{EditCheckName: "ACTIVATED", FormOID: "DM", IsActive: 1},
+		{EditCheckName: "DEACTIVATED", FormOID: "DM", IsActive: 0},
+		{EditCheckName: "MOVED", FormOID: "DM", FieldOID: "BRTHDAT", IsActive: 1},
+		{EditCheckName: "ACTIONS", FormOID: "DM", Actions: "OpenQuery|CustomFunction", IsActive: 1},
+		{EditCheckName: "BOTH", FormOID: "AE", IsActive: 0},
+		// the rows sharing a name are combined, so the order doesn't matter
+		{EditCheckName: "SAME", FormOID: "DM", FieldOID: "BRTHDAT", IsActive: 1},
+		{EditCheckName: "SAME", FormOID: "DM", FieldOID: "AGE", IsActive: 0},
+	})
+	expected := []struct {
+		name        string
+		description string
+		hasOld      bool
+		hasNew      bool
+	}{
+		{"ACTIONS", "Modified", true, true},
+		{"ACTIVATED", "Activated", true, true},
+		{"ADDED", "Added", false, true},
+		{"BOTH", "Deactivated, Modified", true, true},
+		{"DEACTIVATED", "Deactivated", true, true},
+		{"MOVED", "Modified", true, true},
+		{"REMOVED", "Removed", true, false},
+	}
+	changes := diffEditChecks(100, 101, oldChecks, newChecks)
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), changes)
+	}
+	for idx, change := range changes {
+		test := expected[idx]
+		if change.EditCheckName != test.name || change.description() != test.description {
+			t.Errorf("change %d: expected %s %q, got %s %q", idx, test.name, test.description,
+				change.EditCheckName, change.description())
+		}
+		if (change.Old != nil) != test.hasOld || (change.New != nil) != test.hasNew {
+			t.Errorf("%s: expected the old check %v and the new check %v", test.name, test.hasOld, test.hasNew)
+		}
+		if change.FromVersion != 100 || change.ToVersion != 101 {
+			t.Errorf("%s: expected versions 100 to 101, got %d to %d", test.name, change.FromVersion, change.ToVersion)
+		}
+	}
+}
+
+func TestDiffEditChecksModifiedOIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new EditCheck
+		expected string
+	}{
+		{"form", EditCheck{FormOID: "DM"}, EditCheck{FormOID: "DEMOG"}, "Modified"},
+		{"field", EditCheck{FieldOID: "AGE"}, EditCheck{FieldOID: "BRTHDAT"}, "Modified"},
+		{"variable", EditCheck{VariableOID: "AGE"}, EditCheck{VariableOID: "AGE_RAW"}, "Modified"},
+		{"actions", EditCheck{Actions: "OpenQuery"}, EditCheck{Actions: "SetDataPoint"}, "Modified"},
+		{"activated", EditCheck{FormOID: "DM"}, EditCheck{FormOID: "DM", IsActive: 1}, "Activated"},
+		{"activated and modified", EditCheck{FormOID: "DM"}, EditCheck{FormOID: "AE", IsActive: 1}, "Activated, Modified"},
+		{"unchanged", EditCheck{FormOID: "DM", IsActive: 1}, EditCheck{FormOID: "DM", IsActive: 1}, ""},
+	}
+	for _, test := range tests {
+		test.old.EditCheckName, test.new.EditCheckName = "CHECK", "CHECK"
+		changes := diffEditChecks(1, 2,
+			editCheckDefinitions([]*EditCheck{&test.old}), editCheckDefinitions([]*EditCheck{&test.new}))
+		var got string
+		if len(changes) > 0 {
+			got = changes[0].description()
+		}
+		if len(changes) > 1 || got != test.expected {
+			t.Errorf("%s: expected %q, got %+v", test.name, test.expected, changes)
+		}
+	}
+}
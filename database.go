@@ -1,10 +1,35 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/jmoiron/sqlx"
-	"log"
 )
 
+// PostgresStore is a Store backed by the BodyCheck database
+type PostgresStore struct {
+	db *sqlx.DB
+	// limit on how long a single query can run, zero for no limit
+	queryTimeout time.Duration
+	// how the edit checks are classified
+	rules *ClassificationRules
+}
+
+// create a Store for the database connection
+func newPostgresStore(db *sqlx.DB, queryTimeout time.Duration, rules *ClassificationRules) *PostgresStore {
+	return &PostgresStore{db: db, queryTimeout: queryTimeout, rules: rules}
+}
+
+// derive the context for a single query
+func (s *PostgresStore) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout > 0 {
+		return context.WithTimeout(ctx, s.queryTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
 // does the pattern return any Rave URLS by name
 //func doesPatternMatch(pattern string, db *sqlx.DB) bool {
 //	q := `SELECT COUNT(*) FROM rave_url
@@ -18,42 +43,46 @@ import (
 //}
 
 // get RaveURLS that match the pattern
-func GetURLsThatMatch(db *sqlx.DB, pattern string) (urls []RaveURL, err error) {
+func (s *PostgresStore) GetURLsThatMatch(ctx context.Context, matcher *URLMatcher) (urls []RaveURL, err error) {
+	condition, pattern := matcher.sqlCondition()
 	q := `SELECT id, url, alternate_url FROM rave_url 
-		WHERE rave_url.url LIKE '%' || $1 || '%' 
-		OR rave_url.alternate_url LIKE '%' || $1 || '%' `
-	rows, err := db.Queryx(q, pattern)
+		WHERE ` + condition + `
+		ORDER BY url, alternate_url`
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q, pattern)
 	if err != nil {
 		return
 	}
 	defer func() {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
 	// iterate over rows
 	for rows.Next() {
 		var r RaveURL
-		if err := rows.StructScan(&r); err != nil {
-			log.Fatal(err)
+		if err = rows.StructScan(&r); err != nil {
+			return
 		}
 		urls = append(urls, r)
 	}
+	err = rows.Err()
 	return
 }
 
 // dump a list of URLs
-func listURLs(db *sqlx.DB) (urls []string, err error) {
+func (s *PostgresStore) listURLs(ctx context.Context) (urls []string, err error) {
 	q := `SELECT url, alternate_url FROM rave_url ORDER BY url, alternate_url`
-	rows, err := db.Queryx(q)
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q)
 	if err != nil {
 		return
 	}
 	defer func() {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
 	// iterate over rows
@@ -71,41 +100,44 @@ func listURLs(db *sqlx.DB) (urls []string, err error) {
 			urls = append(urls, mainURL)
 		}
 	}
+	err = rows.Err()
 	return
 }
 
 // get the Projects for a URL
-func getProjects(db *sqlx.DB, urlID int) (projects []*Project) {
+func (s *PostgresStore) getProjects(ctx context.Context, urlID int) (projects []*Project, err error) {
 	// NOTE: project.id is the autogenerated value
 	q := `SELECT DISTINCT prj.url_id,
 		   prj.id AS project_id,
 		   prj.project_name
 	FROM project prj
 	WHERE prj.url_id = $1`
-	rows, err := db.Queryx(q, urlID)
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q, urlID)
 	if err != nil {
-		log.Fatal("PJ Query failed: ", err)
+		return nil, fmt.Errorf("PJ Query failed: %w", err)
 	}
 	defer func() {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
 	// iterate over rows
 	for rows.Next() {
 		var r Project
-		if err := rows.StructScan(&r); err != nil {
-			log.Fatal(err)
+		if err = rows.StructScan(&r); err != nil {
+			return
 		}
 		// log.Println("Project",r.ProjectName,"(",r.ProjectID,")")
 		projects = append(projects, &r)
 	}
+	err = rows.Err()
 	return
 }
 
 // Get the project versions
-func getProjectVersions(db *sqlx.DB, projectID int) (projectVersions []*ProjectVersion) {
+func (s *PostgresStore) getProjectVersions(ctx context.Context, projectID int) (projectVersions []*ProjectVersion, err error) {
 	q := `SELECT DISTINCT
                 edt.project_id AS project_id,
                 edt.crf_version_id AS crf_version_id,
@@ -114,30 +146,30 @@ func getProjectVersions(db *sqlx.DB, projectID int) (projectVersions []*ProjectV
 				LEFT JOIN project_last_version plv ON edt.project_id = plv.project_id
 			WHERE edt.project_id = $1
 			`
-	rows, err := db.Queryx(q, projectID)
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q, projectID)
 	if err != nil {
-		log.Fatal("PV Query failed: ", err)
+		return nil, fmt.Errorf("PV Query failed: %w", err)
 	}
 	defer func() {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
 	// iterate over rows
 	for rows.Next() {
 		var r ProjectVersion
-		if err := rows.StructScan(&r); err != nil {
-			log.Fatal(err)
+		if err = rows.StructScan(&r); err != nil {
+			return
 		}
 		projectVersions = append(projectVersions, &r)
 	}
+	err = rows.Err()
 	return
-
 }
 
-func getSubjectCounts(db *sqlx.DB, urlID int) []SubjectCount {
-	var subjectCounts []SubjectCount
+func (s *PostgresStore) getSubjectCounts(ctx context.Context, urlID int) (subjectCounts []SubjectCount, err error) {
 	q := `SELECT
 	edt.url_id,
 	edt.project_id AS project_id,
@@ -155,14 +187,15 @@ FROM edit_check edt
     JOIN refresh_date rd on pj.id = rd.project_id
   WHERE edt.url_id = $1
 GROUP BY edt.url_id, edt.project_id, pj.project_name, rd.refresh_date;`
-	rows, err := db.Queryx(q, urlID)
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q, urlID)
 	if err != nil {
-		log.Fatal("SBJS Query failed: ", err)
+		return nil, fmt.Errorf("SBJS Query failed: %w", err)
 	}
 	defer func() {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
 
@@ -170,17 +203,18 @@ GROUP BY edt.url_id, edt.project_id, pj.project_name, rd.refresh_date;`
 	for rows.Next() {
 
 		var r SubjectCount
-		if err := rows.StructScan(&r); err != nil {
-			log.Fatal(err)
+		if err = rows.StructScan(&r); err != nil {
+			return
 		}
 		//  log.Println(r.ProjectName, "Loaded: ", r.SubjectCount,"for ProjectID",r.ProjectID)
 		subjectCounts = append(subjectCounts, r)
 	}
-	return subjectCounts
+	err = rows.Err()
+	return
 }
 
 // export the subject counts
-func getProjectSubjectCount(db *sqlx.DB, urlID, projectID int) (subjectCount SubjectCount) {
+func (s *PostgresStore) getProjectSubjectCount(ctx context.Context, urlID, projectID int) (subjectCount SubjectCount, err error) {
 	q := `SELECT
     edt.project_id AS project_id,
     pj.project_name AS project_name,
@@ -197,64 +231,34 @@ FROM edit_check edt
     JOIN refresh_date rd on pj.id = rd.project_id
   WHERE edt.url_id = $1 AND edt.project_id = $2
 GROUP BY edt.project_id, pj.project_name, rd.refresh_date;`
-	rows, err := db.Queryx(q, urlID, projectID)
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q, urlID, projectID)
 	if err != nil {
-		log.Fatal("SBJ Query failed: ", err)
+		return subjectCount, fmt.Errorf("SBJ Query failed: %w", err)
 	}
 	defer func() {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
 
 	// iterate over rows
 	for rows.Next() {
-		if err := rows.StructScan(subjectCount); err != nil {
-			log.Fatal(err)
+		if err = rows.StructScan(&subjectCount); err != nil {
+			return
 		}
 	}
+	err = rows.Err()
 	return
 }
 
-func hasCustomFunction(db *sqlx.DB, projectID int, editCheckName string) bool {
-	q := `SELECT project_id,
-       edit_check_name,
-       SUM(CASE WHEN actions LIKE '%CustomFunction%' THEN 1 ELSE 0 END) AS cf_count
-FROM edit_check
-WHERE project_id = $1 AND edit_check_name = $2
-GROUP BY project_id, edit_check_name`
-	rows, err := db.Queryx(q, projectID, editCheckName)
-	if err != nil {
-		log.Fatal("CF Query failed: ", err)
-	}
-	defer func() {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
-	type editResult struct {
-		ProjectID     int    `db:"project_id"`
-		EditCheckName string `db:"edit_check_name"`
-		CFCount       int    `db:"cf_count"`
-	}
-	// Export the results
-	for rows.Next() {
-		var r editResult
-		if err := rows.StructScan(&r); err != nil {
-			log.Fatal(err)
-		}
-		return r.CFCount > 0
-	}
-	return false
-}
-
-func getUselessEditsForProject(db *sqlx.DB, projectID int, withOpenQueryFilter EditCheckOutcome) []*UnusedEdit {
-	var unusedEdits []*UnusedEdit
-	q := `SELECT project_id,
-        edit_check_name AS edit_check_name,
+func (s *PostgresStore) getUselessEditsForProject(ctx context.Context, projectID int, withOpenQueryFilter EditCheckOutcome) (unusedEdits []*UnusedEdit, err error) {
+	q := `SELECT total.project_id,
+        total.edit_check_name AS edit_check_name,
         total_count,
+        -- does any version of the check call a CustomFunction
+        COALESCE(cf.cf_count, 0) > 0                                                           AS custom_function,
        (SELECT array_to_string(array_remove(array_agg(DISTINCT chk.form_oid), NULL), '|')
         FROM edit_check chk
         WHERE chk.edit_check_name = total.edit_check_name)                                     AS form_oids,
@@ -263,7 +267,11 @@ func getUselessEditsForProject(db *sqlx.DB, projectID int, withOpenQueryFilter E
         WHERE chk.edit_check_name = total.edit_check_name)                                     AS field_oids,
        (SELECT array_to_string(array_remove(array_agg(DISTINCT chk.variable_oid), NULL), '|')
         FROM edit_check chk
-        WHERE chk.edit_check_name = total.edit_check_name)                                     AS variable_oids
+        WHERE chk.edit_check_name = total.edit_check_name)                                     AS variable_oids,
+       (SELECT array_to_string(array_remove(array_agg(DISTINCT chk.actions), NULL), '|')
+        FROM edit_check chk
+        WHERE chk.edit_check_name = total.edit_check_name
+          AND chk.project_id = total.project_id)                                               AS actions
  FROM (SELECT project_id,
                edit_check_name,
                COUNT(*) as total_count,
@@ -276,40 +284,49 @@ func getUselessEditsForProject(db *sqlx.DB, projectID int, withOpenQueryFilter E
 		        actions NOT LIKE '%OpenQuery%'
 	        END
       GROUP BY edit_check_name, project_id) total
+    LEFT JOIN (SELECT edit_check_name,
+               SUM(CASE WHEN actions LIKE '%CustomFunction%' THEN 1 ELSE 0 END) AS cf_count
+        FROM edit_check
+        WHERE project_id = $1
+        GROUP BY edit_check_name) cf ON cf.edit_check_name = total.edit_check_name
 WHERE
 	total.total_executions = 0
-GROUP BY project_id, edit_check_name, total_count`
-	rows, err := db.Queryx(q, projectID, int(withOpenQueryFilter))
+GROUP BY total.project_id, total.edit_check_name, total_count, cf.cf_count`
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q, projectID, int(withOpenQueryFilter))
 	if err != nil {
-		log.Fatal("BE Query failed: ", err)
+		return nil, fmt.Errorf("BE Query failed: %w", err)
 	}
 	defer func() {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
 	// Export the results
 	for rows.Next() {
 		var r UnusedEdit
-		if err := rows.StructScan(&r); err != nil {
-			log.Fatal(err)
-		}
-		if hasCustomFunction(db, r.ProjectID, r.EditCheckName) {
-			r.CustomFunction = true
-		} else {
-			r.CustomFunction = false
+		if err = rows.StructScan(&r); err != nil {
+			return
 		}
 		unusedEdits = append(unusedEdits, &r)
 	}
-	return unusedEdits
+	err = rows.Err()
+	return
 }
 
-// get the summary by type
-func getStudyMetricsByProjectAndCheckType(db *sqlx.DB, projectID, crfVersionID int, checkType EditCheckClass) (metrics EditTypeMetric) {
+// get the summary by type for every version of every project in a URL
+func (s *PostgresStore) getVersionMetricsForURL(ctx context.Context, urlID int) (metrics []VersionEditTypeMetric, err error) {
+	args := []interface{}{urlID}
 	q := `SELECT 
+		edt.project_id													AS project_id
+		, edt.crf_version_id											AS crf_version_id
+		-- field or programmed edits, from the classification rules
+		, ` + s.rules.checkTypeSQL(&args) + `							AS check_type
+		-- the active and inactive checks are summed separately
+		, edt.is_active													AS is_active
 		-- total edits per version
-		COUNT(*) 														AS total_edits
+		, COUNT(*) 														AS total_edits
 		-- total edits with OpenQuery action (filtered to active only)
 		, SUM(CASE WHEN actions LIKE '%OpenQuery%'
 				  THEN 1
@@ -349,7 +366,7 @@ func getStudyMetricsByProjectAndCheckType(db *sqlx.DB, projectID, crfVersionID i
 				ELSE 0 END)                                             AS total_edits_fired_with_change
 		-- count of checks that have fired, but never led to a change in the data
 		, SUM(CASE
-				WHEN change_count = 0 AND no_change_count > 0 AND is_active = 1
+				WHEN change_count = 0 AND no_change_count > 0
 				  THEN 1
 				ELSE 0 END)                                             AS total_edits_fired_without_change
 		-- total count of changes 
@@ -362,70 +379,136 @@ func getStudyMetricsByProjectAndCheckType(db *sqlx.DB, projectID, crfVersionID i
                 THEN 1
 				ELSE 0 END)                                             AS total_open_edits
 	FROM edit_check edt
-	WHERE edt.project_id = $1 AND edt.crf_version_id = $2
-		AND CASE WHEN 1 = 1 THEN
-			-- pick only active checks
-			edt.is_active = 1
-		ELSE
-			-- pick only inactive checks
-			edt.is_active = 0
-		END
-		AND CASE WHEN $3 = 0 THEN
-			-- field edits
-			edit_check_name LIKE 'SYS_%'
-		ELSE
-			-- programmed edits
-			edit_check_name NOT LIKE 'SYS_%'
-		END
-	GROUP BY edt.project_id, edt.crf_version_id
+		JOIN project prj ON edt.project_id = prj.id
+	WHERE prj.url_id = $1
+		AND edt.is_active IN (0, 1)
+	GROUP BY edt.project_id, edt.crf_version_id, check_type, edt.is_active
 	`
-	rows, err := db.Queryx(q, projectID, crfVersionID, int(checkType))
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q, args...)
 	if err != nil {
-		log.Fatal("SM Query failed: ", err)
+		return nil, fmt.Errorf("SM Query failed: %w", err)
 	}
 	defer func() {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
 
 	// iterate over rows
 	for rows.Next() {
-		if err := rows.StructScan(&metrics); err != nil {
-			log.Fatal(err)
+		var r VersionEditTypeMetric
+		if err = rows.StructScan(&r); err != nil {
+			return
 		}
+		metrics = append(metrics, r)
 	}
+	err = rows.Err()
 	return
 }
 
-// get the counts by edit check status
-func getActivityCount(db *sqlx.DB, projectID, crfVersionID int) EditStatusCounts {
-	var editStatusCounts EditStatusCounts
-	q := `SELECT SUM(CASE WHEN is_active = 1 THEN 1 ELSE 0 END) AS active_count,
+// get the counts by edit check status for every version of every project in a URL
+func (s *PostgresStore) getActivityCountsForURL(ctx context.Context, urlID int) (statusCounts []VersionStatusCounts, err error) {
+	q := `SELECT edt.project_id AS project_id,
+       edt.crf_version_id AS crf_version_id,
+       SUM(CASE WHEN is_active = 1 THEN 1 ELSE 0 END) AS active_count,
        SUM(CASE WHEN is_active = 0 THEN 1 ELSE 0 END) AS inactive_count
 		FROM edit_check edt
-	WHERE edt.project_id = $1 AND edt.crf_version_id = $2
+			JOIN project prj ON edt.project_id = prj.id
+	WHERE prj.url_id = $1
 	GROUP BY edt.project_id, edt.crf_version_id
 	`
-	rows, err := db.Queryx(q, projectID, crfVersionID)
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q, urlID)
 	if err != nil {
-		log.Fatal("AC Query failed: ", err)
+		return nil, fmt.Errorf("AC Query failed: %w", err)
 	}
 	defer func() {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
 	for rows.Next() {
-		if err := rows.StructScan(&editStatusCounts); err != nil {
-			log.Fatal(err)
+		var r VersionStatusCounts
+		if err = rows.StructScan(&r); err != nil {
+			return
 		}
+		statusCounts = append(statusCounts, r)
 	}
-	//log.Println("Loaded Status Counts for",
-	//	projectID, "(",
-	//	crfVersionID, ") with active edits = ", editStatusCounts.ActiveEdits)
+	err = rows.Err()
+	return
+}
 
-	return editStatusCounts
+// get the edit checks for every version of every project in a URL
+func (s *PostgresStore) getEditChecksForURL(ctx context.Context, urlID int) (editChecks []EditCheck, err error) {
+	q := `SELECT edt.project_id AS project_id,
+       edt.crf_version_id AS crf_version_id,
+       edt.edit_check_name AS edit_check_name,
+       COALESCE(edt.form_oid, '') AS form_oid,
+       COALESCE(edt.field_oid, '') AS field_oid,
+       COALESCE(edt.variable_oid, '') AS variable_oid,
+       COALESCE(edt.actions, '') AS actions,
+       COALESCE(edt.is_active, 0) AS is_active,
+       COALESCE(edt.total_check_executions, 0) AS total_check_executions,
+       COALESCE(edt.open_checks, 0) AS open_checks,
+       COALESCE(edt.change_count, 0) AS change_count,
+       COALESCE(edt.no_change_count, 0) AS no_change_count
+		FROM edit_check edt
+			JOIN project prj ON edt.project_id = prj.id
+	WHERE prj.url_id = $1
+	ORDER BY edt.project_id, edt.crf_version_id, edt.edit_check_name
+	`
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q, urlID)
+	if err != nil {
+		return nil, fmt.Errorf("EC Query failed: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	for rows.Next() {
+		var r EditCheck
+		if err = rows.StructScan(&r); err != nil {
+			return
+		}
+		editChecks = append(editChecks, r)
+	}
+	err = rows.Err()
+	return
+}
+
+// get the distinct edit check names of every project in a URL
+func (s *PostgresStore) getEditCheckNamesForURL(ctx context.Context, urlID int) (names []ProjectEditCheckName, err error) {
+	q := `SELECT DISTINCT edt.project_id AS project_id,
+       edt.edit_check_name AS edit_check_name
+		FROM edit_check edt
+			JOIN project prj ON edt.project_id = prj.id
+	WHERE prj.url_id = $1
+	ORDER BY edt.project_id, edt.edit_check_name
+	`
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	rows, err := s.db.QueryxContext(ctx, q, urlID)
+	if err != nil {
+		return nil, fmt.Errorf("EC Name Query failed: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	for rows.Next() {
+		var r ProjectEditCheckName
+		if err = rows.StructScan(&r); err != nil {
+			return
+		}
+		names = append(names, r)
+	}
+	err = rows.Err()
+	return
 }
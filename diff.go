@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tealeg/xlsx"
+)
+
+// the prefix for a stored run, eg run:42
+const runSourcePrefix = "run:"
+
+// load a JSON export, checking it has the schema we understand
+func loadJSONReport(fileName string) (JSONReport, error) {
+	var report JSONReport
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(content, &report); err != nil {
+		return report, fmt.Errorf("parsing %s: %w", fileName, err)
+	}
+	if report.SchemaVersion != reportSchemaVersion {
+		return report, fmt.Errorf("%s has schema version %d, expected %d",
+			fileName, report.SchemaVersion, reportSchemaVersion)
+	}
+	return report, nil
+}
+
+// compare two runs, each a JSON export or a stored run, and write the workbook of changes
+func runDiff(ctx context.Context, sources []string, job ReportJob, history HistorySettings, connection ConnectionSettings) error {
+	if len(sources) != 2 {
+		return fmt.Errorf("diff needs the old and new runs, eg diff old.json new.json or diff run:1 run:2")
+	}
+	options, err := job.reportOptions(1)
+	if err != nil {
+		return err
+	}
+	var historyStore *HistoryStore
+	load := func(source string) (JSONReport, error) {
+		if !strings.HasPrefix(source, runSourcePrefix) {
+			return loadJSONReport(source)
+		}
+		runID, err := strconv.ParseInt(strings.TrimPrefix(source, runSourcePrefix), 10, 64)
+		if err != nil {
+			return JSONReport{}, fmt.Errorf("invalid run %q", source)
+		}
+		if historyStore == nil {
+			if history.Schema == "" {
+				return JSONReport{}, fmt.Errorf("%s needs the snapshot schema (-history-schema)", source)
+			}
+			// the snapshots live in the report database unless they have their own
+			var reportDB *sqlx.DB
+			if history.DSN == "" {
+				dataSourceName, err := connection.dataSourceName()
+				if err != nil {
+					return JSONReport{}, err
+				}
+				if reportDB, err = sqlx.Open("postgres", dataSourceName); err != nil {
+					return JSONReport{}, err
+				}
+			}
+			if historyStore, err = openHistoryStore(ctx, history, reportDB); err != nil {
+				return JSONReport{}, fmt.Errorf("unable to open the snapshot store: %w", err)
+			}
+		}
+		return historyStore.loadSnapshot(ctx, runID)
+	}
+	var reports [2]JSONReport
+	var sides [2]RunSide
+	for idx, source := range sources {
+		if reports[idx], err = load(source); err != nil {
+			return err
+		}
+		sides[idx] = RunSide{Source: source, GeneratedAt: reports[idx].GeneratedAt, Job: reports[idx].Job}
+	}
+	if reports[0].RaveURL.URL != reports[1].RaveURL.URL {
+		return fmt.Errorf("the runs are for different URLs, %s and %s", reports[0].RaveURL.URL, reports[1].RaveURL.URL)
+	}
+	delta := newRunDelta(reports[0], reports[1], sides[0], sides[1])
+	workbook := xlsx.NewFile()
+	if err := writeRunDelta(delta, workbook); err != nil {
+		return err
+	}
+	// named like the report, with a _diff suffix
+	raveURL := RaveURL{PreferredURL: delta.URL, URLID: reports[1].RaveURL.URLID, Domain: options.Domain}
+	fileName := replaceExtension(options.reportFileName(raveURL, time.Now()), "") + "_diff.xlsx"
+	saved, err := writeFileAtomic(fileName, options.OnCollision, workbook.Write)
+	if err != nil {
+		return err
+	}
+	log.Println("Saved", saved)
+	return nil
+}
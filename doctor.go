@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// a table and the columns the queries rely upon
+type schemaRequirement struct {
+	Table   string
+	Columns []string
+}
+
+// the tables and columns used by the queries in database.go
+var requiredColumns = []schemaRequirement{
+	{"rave_url", []string{"id", "url", "alternate_url"}},
+	{"project", []string{"id", "url_id", "project_name"}},
+	{"project_last_version", []string{"project_id", "crf_version_id"}},
+	{"refresh_date", []string{"project_id", "refresh_date"}},
+	{"edit_check", []string{"url_id",
+		"project_id",
+		"crf_version_id",
+		"edit_check_name",
+		"form_oid",
+		"field_oid",
+		"variable_oid",
+		"actions",
+		"is_active",
+		"total_check_executions",
+		"open_checks",
+		"change_count",
+		"no_change_count",
+		"subject_count",
+		"screening_subjects",
+		"screening_failure_subjects",
+		"enrolled_subjects",
+		"completed_subjects",
+		"enrolled_follow_up_subjects",
+		"early_terminated_subjects",
+	}},
+}
+
+// the columns the queries filter and join on, each must lead an index
+var requiredIndexes = []schemaRequirement{
+	{"project", []string{"id"}},
+	{"project", []string{"url_id"}},
+	{"project_last_version", []string{"project_id"}},
+	{"refresh_date", []string{"project_id"}},
+	{"edit_check", []string{"project_id"}},
+	{"edit_check", []string{"url_id"}},
+	{"edit_check", []string{"edit_check_name"}},
+}
+
+// the outcome of a single doctor check
+type doctorCheck struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// list the tables named in the requirements
+func requiredTables() (tables []string) {
+	for _, requirement := range requiredColumns {
+		tables = append(tables, requirement.Table)
+	}
+	return
+}
+
+// get the columns for each of the tables
+func getTableColumns(ctx context.Context, db *sqlx.DB, tables []string) (columns map[string]map[string]bool, err error) {
+	q := `SELECT table_name, column_name
+	FROM information_schema.columns
+	WHERE table_schema = current_schema() AND table_name = ANY($1)`
+	rows, err := db.QueryxContext(ctx, q, pq.Array(tables))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	columns = make(map[string]map[string]bool)
+	for rows.Next() {
+		var tableName, columnName string
+		if err = rows.Scan(&tableName, &columnName); err != nil {
+			return
+		}
+		if columns[tableName] == nil {
+			columns[tableName] = make(map[string]bool)
+		}
+		columns[tableName][columnName] = true
+	}
+	err = rows.Err()
+	return
+}
+
+// get the ordered columns of each index for each of the tables
+func getTableIndexes(ctx context.Context, db *sqlx.DB, tables []string) (indexes map[string][][]string, err error) {
+	q := `SELECT t.relname AS table_name,
+       array_to_string(ARRAY(
+           SELECT a.attname
+           FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+               JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+           ORDER BY k.ord), ',') AS index_columns
+	FROM pg_class t
+		JOIN pg_index ix ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+	WHERE n.nspname = current_schema() AND t.relname = ANY($1)`
+	rows, err := db.QueryxContext(ctx, q, pq.Array(tables))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	indexes = make(map[string][][]string)
+	for rows.Next() {
+		var tableName, indexColumns string
+		if err = rows.Scan(&tableName, &indexColumns); err != nil {
+			return
+		}
+		indexes[tableName] = append(indexes[tableName], strings.Split(indexColumns, ","))
+	}
+	err = rows.Err()
+	return
+}
+
+// is there an index led by the columns
+func hasLeadingIndex(indexes [][]string, columns []string) bool {
+	for _, index := range indexes {
+		if len(index) < len(columns) {
+			continue
+		}
+		matched := true
+		for idx, column := range columns {
+			if index[idx] != column {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ping the database within the query timeout
+func pingDatabase(ctx context.Context, store *PostgresStore) error {
+	ctx, cancel := store.queryContext(ctx)
+	defer cancel()
+	return store.db.PingContext(ctx)
+}
+
+// check the connection, tables, columns and indexes the report relies upon, each query
+// limited by the query timeout of the store
+func checkDatabase(ctx context.Context, store *PostgresStore) (checks []doctorCheck) {
+	if err := pingDatabase(ctx, store); err != nil {
+		return append(checks, doctorCheck{Name: "database connection", Detail: err.Error()})
+	}
+	checks = append(checks, doctorCheck{Name: "database connection", Passed: true})
+	tables := requiredTables()
+	columnsCtx, cancel := store.queryContext(ctx)
+	columns, err := getTableColumns(columnsCtx, store.db, tables)
+	cancel()
+	if err != nil {
+		return append(checks, doctorCheck{Name: "schema columns", Detail: err.Error()})
+	}
+	for _, requirement := range requiredColumns {
+		tableColumns, ok := columns[requirement.Table]
+		if !ok {
+			checks = append(checks, doctorCheck{Name: "table " + requirement.Table, Detail: "missing"})
+			continue
+		}
+		checks = append(checks, doctorCheck{Name: "table " + requirement.Table, Passed: true})
+		for _, column := range requirement.Columns {
+			check := doctorCheck{Name: "column " + requirement.Table + "." + column, Passed: tableColumns[column]}
+			if !check.Passed {
+				check.Detail = "missing"
+			}
+			checks = append(checks, check)
+		}
+	}
+	indexesCtx, cancel := store.queryContext(ctx)
+	indexes, err := getTableIndexes(indexesCtx, store.db, tables)
+	cancel()
+	if err != nil {
+		return append(checks, doctorCheck{Name: "schema indexes", Detail: err.Error()})
+	}
+	for _, requirement := range requiredIndexes {
+		check := doctorCheck{
+			Name:   fmt.Sprintf("index on %s (%s)", requirement.Table, strings.Join(requirement.Columns, ", ")),
+			Passed: hasLeadingIndex(indexes[requirement.Table], requirement.Columns),
+		}
+		if !check.Passed {
+			check.Detail = "no index leads with these columns"
+		}
+		checks = append(checks, check)
+	}
+	return
+}
+
+// print the pass/fail report, returning whether all the checks passed
+func runDoctor(ctx context.Context, store *PostgresStore, out io.Writer) bool {
+	failed := 0
+	checks := checkDatabase(ctx, store)
+	for _, check := range checks {
+		status := "PASS"
+		if !check.Passed {
+			status = "FAIL"
+			failed++
+		}
+		if check.Detail != "" {
+			fmt.Fprintf(out, "%s  %s: %s\n", status, check.Name, check.Detail)
+		} else {
+			fmt.Fprintf(out, "%s  %s\n", status, check.Name)
+		}
+	}
+	fmt.Fprintf(out, "%d checks, %d failed\n", len(checks), failed)
+	return failed == 0
+}
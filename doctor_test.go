@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestHasLeadingIndex(t *testing.T) {
+	indexes := [][]string{
+		{"id"},
+		{"project_id", "crf_version_id"},
+		{"url_id", "project_id"},
+	}
+	tests := []struct {
+		columns  []string
+		expected bool
+	}{
+		{[]string{"id"}, true},
+		{[]string{"project_id"}, true},
+		{[]string{"project_id", "crf_version_id"}, true},
+		{[]string{"url_id", "project_id"}, true},
+		// the columns must lead the index, in order
+		{[]string{"crf_version_id"}, false},
+		{[]string{"crf_version_id", "project_id"}, false},
+		{[]string{"project_id", "url_id"}, false},
+		// longer than any index
+		{[]string{"project_id", "crf_version_id", "edit_check_name"}, false},
+		{[]string{"edit_check_name"}, false},
+	}
+	for _, test := range tests {
+		if got := hasLeadingIndex(indexes, test.columns); got != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.columns, test.expected, got)
+		}
+	}
+	if hasLeadingIndex(nil, []string{"id"}) {
+		t.Error("expected no index on a table without any")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConnectionSettings are the options for connecting to the BodyCheck database,
+// anything left empty is taken from the standard PG* environment variables
+type ConnectionSettings struct {
+	// a complete connection string or URL, used in preference to the other settings
+	DSN         string `json:"dsn"`
+	Host        string `json:"host"`
+	Port        string `json:"port"`
+	DBName      string `json:"dbname"`
+	User        string `json:"user"`
+	SSLMode     string `json:"sslmode"`
+	SSLRootCert string `json:"sslrootcert"`
+}
+
+// fill in any empty settings from the fallback
+func (c ConnectionSettings) withFallback(fallback ConnectionSettings) ConnectionSettings {
+	merged := c
+	for _, setting := range []struct{ value, fallback *string }{
+		{&merged.DSN, &fallback.DSN},
+		{&merged.Host, &fallback.Host},
+		{&merged.Port, &fallback.Port},
+		{&merged.DBName, &fallback.DBName},
+		{&merged.User, &fallback.User},
+		{&merged.SSLMode, &fallback.SSLMode},
+		{&merged.SSLRootCert, &fallback.SSLRootCert},
+	} {
+		if *setting.value == "" {
+			*setting.value = *setting.fallback
+		}
+	}
+	return merged
+}
+
+// the value, falling back to the environment variable and then the default
+func settingOrEnv(value, envName, fallback string) string {
+	if value != "" {
+		return value
+	}
+	if envValue := os.Getenv(envName); envValue != "" {
+		return envValue
+	}
+	return fallback
+}
+
+// quote a value for a key=value connection string
+func quoteDSNValue(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return "'" + escaped + "'"
+}
+
+// are any of the individual settings given, rather than left to the environment
+func (c ConnectionSettings) hasSettings() bool {
+	return c.Host != "" || c.Port != "" || c.DBName != "" || c.User != "" || c.SSLMode != "" || c.SSLRootCert != ""
+}
+
+// resolve the connection string, the password comes from PGPASSWORD or the password file;
+// DATABASE_URL is only used when none of the settings are given
+func (c ConnectionSettings) dataSourceName() (string, error) {
+	if c.DSN != "" {
+		return c.DSN, nil
+	}
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" && !c.hasSettings() {
+		return databaseURL, nil
+	}
+	host := settingOrEnv(c.Host, "PGHOST", "localhost")
+	port := settingOrEnv(c.Port, "PGPORT", "5432")
+	dbName := settingOrEnv(c.DBName, "PGDATABASE", "editsfive")
+	user := settingOrEnv(c.User, "PGUSER", "edits")
+	sslMode := settingOrEnv(c.SSLMode, "PGSSLMODE", "require")
+	sslRootCert := settingOrEnv(c.SSLRootCert, "PGSSLROOTCERT", "")
+	password := os.Getenv("PGPASSWORD")
+	if password == "" {
+		var err error
+		password, err = lookupPassFile(host, port, dbName, user)
+		if err != nil {
+			return "", err
+		}
+	}
+	if password == "" {
+		return "", fmt.Errorf("no password for %s@%s:%s/%s: set PGPASSWORD, add it to %s or supply -dsn",
+			user, host, port, dbName, passFileName())
+	}
+	parts := []string{
+		"host=" + quoteDSNValue(host),
+		"port=" + quoteDSNValue(port),
+		"dbname=" + quoteDSNValue(dbName),
+		"user=" + quoteDSNValue(user),
+		"password=" + quoteDSNValue(password),
+		"sslmode=" + quoteDSNValue(sslMode),
+	}
+	if sslRootCert != "" {
+		parts = append(parts, "sslrootcert="+quoteDSNValue(sslRootCert))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// the location of the password file (PGPASSFILE or ~/.pgpass)
+func passFileName() string {
+	if fileName := os.Getenv("PGPASSFILE"); fileName != "" {
+		return fileName
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".pgpass"
+	}
+	return filepath.Join(home, ".pgpass")
+}
+
+// split a password file line on the unescaped colons
+func splitPassFileLine(line string) []string {
+	var fields []string
+	var field strings.Builder
+	escaped := false
+	for _, char := range line {
+		switch {
+		case escaped:
+			field.WriteRune(char)
+			escaped = false
+		case char == '\\':
+			escaped = true
+		case char == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(char)
+		}
+	}
+	return append(fields, field.String())
+}
+
+// find the password for the connection in the password file, as libpq does
+func lookupPassFile(host, port, dbName, user string) (string, error) {
+	fileName := passFileName()
+	info, err := os.Stat(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		log.Println("Ignoring", fileName, "as it is readable by group or others, it should be 0600")
+		return "", nil
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	wanted := []string{host, port, dbName, user}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitPassFileLine(line)
+		if len(fields) != 5 {
+			continue
+		}
+		matched := true
+		for idx, value := range wanted {
+			if fields[idx] != "*" && fields[idx] != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return fields[4], nil
+		}
+	}
+	return "", scanner.Err()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// set the environment variables for the test, returning a function restoring them
+func setEnv(t *testing.T, values map[string]string) func() {
+	t.Helper()
+	saved := make(map[string]*string)
+	for name, value := range values {
+		if old, ok := os.LookupEnv(name); ok {
+			saved[name] = &old
+		} else {
+			saved[name] = nil
+		}
+		if err := os.Setenv(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return func() {
+		for name, old := range saved {
+			if old == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *old)
+			}
+		}
+	}
+}
+
+// write a password file with the mode, returning its name
+func writePassFile(t *testing.T, dir, content string, mode os.FileMode) string {
+	t.Helper()
+	fileName := filepath.Join(dir, "pgpass")
+	if err := ioutil.WriteFile(fileName, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(fileName, mode); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestSplitPassFileLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"host:5432:db:user:secret", []string{"host", "5432", "db", "user", "secret"}},
+		{"*:*:*:user:secret", []string{"*", "*", "*", "user", "secret"}},
+		{`host:5432:db:user:pass\:word`, []string{"host", "5432", "db", "user", "pass:word"}},
+		{`host:5432:db:user:back\\slash`, []string{"host", "5432", "db", "user", `back\slash`}},
+		{"host:5432:db:user:", []string{"host", "5432", "db", "user", ""}},
+		{"host:5432", []string{"host", "5432"}},
+	}
+	for _, test := range tests {
+		if got := splitPassFileLine(test.line); !equalStrings(got, test.expected) {
+			t.Errorf("splitPassFileLine(%q): expected %q, got %q", test.line, test.expected, got)
+		}
+	}
+}
+
+func TestLookupPassFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "projector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	content := strings.Join([]string{
+		"# comment:*:*:*:ignored",
+		"bodycheck:5432:editsfive:edits:first",
+		`bodycheck:5432:editsfive:other:pass\:word`,
+		"*:*:editsfive:*:wildcard",
+		"short:line",
+	}, "\n")
+	fileName := writePassFile(t, dir, content, 0600)
+	defer setEnv(t, map[string]string{"PGPASSFILE": fileName})()
+	tests := []struct {
+		host, port, dbName, user string
+		expected                 string
+	}{
+		{"bodycheck", "5432", "editsfive", "edits", "first"},
+		{"bodycheck", "5432", "editsfive", "other", "pass:word"},
+		{"elsewhere", "5433", "editsfive", "edits", "wildcard"},
+		{"bodycheck", "5432", "otherdb", "edits", ""},
+	}
+	for _, test := range tests {
+		got, err := lookupPassFile(test.host, test.port, test.dbName, test.user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.expected {
+			t.Errorf("lookupPassFile(%s, %s, %s, %s): expected %q, got %q",
+				test.host, test.port, test.dbName, test.user, test.expected, got)
+		}
+	}
+}
+
+func TestLookupPassFilePermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "projector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := writePassFile(t, dir, "*:*:*:*:secret\n", 0644)
+	defer setEnv(t, map[string]string{"PGPASSFILE": fileName})()
+	// readable by others, so ignored as libpq does
+	got, err := lookupPassFile("bodycheck", "5432", "editsfive", "edits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("expected the password file to be ignored, got %q", got)
+	}
+	// a missing file isn't an error
+	defer setEnv(t, map[string]string{"PGPASSFILE": filepath.Join(dir, "missing")})()
+	if got, err := lookupPassFile("bodycheck", "5432", "editsfive", "edits"); err != nil || got != "" {
+		t.Errorf("expected no password for a missing file, got %q, %v", got, err)
+	}
+}
+
+func TestDataSourceNamePrecedence(t *testing.T) {
+	defer setEnv(t, map[string]string{
+		"DATABASE_URL": "postgres://env@envhost/envdb",
+		"PGPASSWORD":   "secret",
+	})()
+	tests := []struct {
+		name       string
+		connection ConnectionSettings
+		expected   string
+	}{
+		{"dsn", ConnectionSettings{DSN: "host=dsnhost", Host: "flaghost"}, "host=dsnhost"},
+		{"environment", ConnectionSettings{}, "postgres://env@envhost/envdb"},
+		{"host", ConnectionSettings{Host: "flaghost"}, "host='flaghost'"},
+		{"dbname", ConnectionSettings{DBName: "flagdb"}, "dbname='flagdb'"},
+	}
+	for _, test := range tests {
+		got, err := test.connection.dataSourceName()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !strings.Contains(got, test.expected) {
+			t.Errorf("%s: expected %q in %q", test.name, test.expected, got)
+		}
+	}
+}
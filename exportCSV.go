@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tealeg/xlsx"
+)
+
+// the CSV value for a cell, numbers are unformatted and dates are ISO 8601
+func csvCellValue(cell *xlsx.Cell) string {
+	format := cell.GetNumberFormat()
+	if cell.Type() == xlsx.CellTypeNumeric && (format == xlsx.DefaultDateFormat || format == xlsx.DefaultDateTimeFormat) {
+		if timestamp, err := cell.GetTime(false); err == nil {
+			// the serial date is a float, so round off the error
+			return timestamp.Round(time.Second).Format("2006-01-02 15:04:05")
+		}
+	}
+	return cell.Value
+}
+
+// write a sheet as a CSV file
+func writeSheetCSV(sheet *xlsx.Sheet, fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	for _, row := range sheet.Rows {
+		record := make([]string, len(row.Cells))
+		for idx, cell := range row.Cells {
+			record[idx] = csvCellValue(cell)
+		}
+		if err := writer.Write(record); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// write each sheet of the workbook as a CSV file in the directory
+func writeWorkbookCSV(workbook *xlsx.File, dir string) error {
+	for _, sheet := range workbook.Sheets {
+		fileName := filepath.Join(dir, sanitizeFileName(sheet.Name)+".csv")
+		if err := writeSheetCSV(sheet, fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tealeg/xlsx"
+)
+
+func TestCSVCellValue(t *testing.T) {
+	sheet, err := xlsx.NewFile().AddSheet("Values")
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := sheet.AddRow()
+	date := row.AddCell()
+	date.SetDate(time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC))
+	dateTime := row.AddCell()
+	dateTime.SetDateTime(time.Date(2020, 1, 15, 6, 30, 0, 0, time.UTC))
+	percentage := row.AddCell()
+	percentage.SetFloatWithFormat(0.6667, "0.00")
+	count := row.AddCell()
+	count.SetInt(42)
+	text := row.AddCell()
+	text.SetString("OpenQuery|CustomFunction")
+	tests := []struct {
+		cell     *xlsx.Cell
+		expected string
+	}{
+		{date, "2020-01-15 00:00:00"},
+		{dateTime, "2020-01-15 06:30:00"},
+		// numbers are written unformatted
+		{percentage, "0.6667"},
+		{count, "42"},
+		{text, "OpenQuery|CustomFunction"},
+	}
+	for _, test := range tests {
+		if got := csvCellValue(test.cell); got != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, got)
+		}
+	}
+}
+
+func TestWorkbookCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "projector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	job := defaultReportJob()
+	job.EditStatus = AllChecks.String()
+	workbook := fixtureWorkbook(t, "pharma.mdsol.com", job)
+	job.Format = FormatCSV.String()
+	raveURL := runFixtureReport(t, "pharma.mdsol.com", job, 1, dir)
+	reportDir := filepath.Join(dir, raveURL.URLPrefix())
+
+	files, err := ioutil.ReadDir(reportDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(workbook.Sheets) {
+		t.Errorf("expected a CSV file for each of the %d sheets, got %d", len(workbook.Sheets), len(files))
+	}
+	var dates int
+	for _, sheet := range workbook.Sheets {
+		file, err := os.Open(filepath.Join(reportDir, sanitizeFileName(sheet.Name)+".csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(file).ReadAll()
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", sheet.Name, err)
+		}
+		if len(records) != len(sheet.Rows) {
+			t.Errorf("%s: expected %d rows, got %d", sheet.Name, len(sheet.Rows), len(records))
+			continue
+		}
+		for rowIdx, row := range sheet.Rows {
+			if len(records[rowIdx]) != len(row.Cells) {
+				t.Errorf("%s row %d: expected %d cells, got %v", sheet.Name, rowIdx, len(row.Cells), records[rowIdx])
+				continue
+			}
+			for idx, cell := range row.Cells {
+				got := records[rowIdx][idx]
+				if got == cell.Value {
+					continue
+				}
+				// only the dates differ from the stored values, as ISO 8601
+				expected, err := cell.GetTime(false)
+				if err != nil || got != expected.Round(time.Second).Format("2006-01-02 15:04:05") {
+					t.Errorf("%s row %d cell %d: expected %q, got %q", sheet.Name, rowIdx, idx, cell.Value, got)
+				}
+				dates++
+			}
+		}
+	}
+	// the refresh dates of the subject counts
+	if dates != 2 {
+		t.Errorf("expected the two refresh dates to be formatted, got %d", dates)
+	}
+
+	file, err := os.Open(filepath.Join(reportDir, "Subject Counts.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alpha := records[1]; alpha[1] != "Alpha" || alpha[len(alpha)-1] != "2020-01-15 00:00:00" {
+		t.Errorf("expected Alpha refreshed on 2020-01-15, got %v", alpha)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/tealeg/xlsx"
+)
+
+// the width of the bar area in the charts, in pixels
+const chartWidth = 600.0
+
+// a part of a stacked bar
+type chartSegment struct {
+	Label string
+	Value int
+	X     float64
+	Width float64
+	Class string
+}
+
+// a stacked bar for a project
+type chartBar struct {
+	Label    string
+	Y        int
+	Total    int
+	Segments []chartSegment
+}
+
+// a horizontal stacked bar chart, one bar per project
+type htmlChart struct {
+	Title  string
+	Legend []chartSegment
+	Bars   []chartBar
+	Height int
+}
+
+// the content of the HTML report
+type htmlReport struct {
+	URL         string
+	GeneratedAt string
+	Charts      []htmlChart
+	Tables      []reportTable
+}
+
+// a negative value means the metric wasn't recorded
+func chartValue(value int) int {
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
+// build a stacked bar chart over the last versions of the projects
+func newHTMLChart(title string, labels, classes []string, projects []*Project, values func(*ProjectVersion) []int) htmlChart {
+	chart := htmlChart{Title: title}
+	for idx, label := range labels {
+		chart.Legend = append(chart.Legend, chartSegment{Label: label, Class: classes[idx]})
+	}
+	maxTotal := 0
+	for _, project := range projects {
+		lastVersion := project.getLastVersion()
+		if lastVersion == nil {
+			continue
+		}
+		bar := chartBar{Label: project.ProjectName, Y: len(chart.Bars) * 24}
+		for idx, value := range values(lastVersion) {
+			value = chartValue(value)
+			bar.Segments = append(bar.Segments, chartSegment{Label: labels[idx], Value: value, Class: classes[idx]})
+			bar.Total += value
+		}
+		if bar.Total > maxTotal {
+			maxTotal = bar.Total
+		}
+		chart.Bars = append(chart.Bars, bar)
+	}
+	// scale the bars to the largest
+	for barIdx := range chart.Bars {
+		x := 0.0
+		for idx, segment := range chart.Bars[barIdx].Segments {
+			width := 0.0
+			if maxTotal > 0 {
+				width = chartWidth * float64(segment.Value) / float64(maxTotal)
+			}
+			chart.Bars[barIdx].Segments[idx].X = x
+			chart.Bars[barIdx].Segments[idx].Width = width
+			x += width
+		}
+	}
+	chart.Height = len(chart.Bars)*24 + 4
+	return chart
+}
+
+// the charts for the last versions of the projects, over the active or inactive edits
+func newHTMLCharts(projects []*Project, status EditStatusFilter) []htmlChart {
+	suffix := status.sheetSuffix()
+	return []htmlChart{
+		newHTMLChart("Checks with OpenQuery fired vs not fired"+suffix, []string{"Fired with OpenQuery", "Not Fired with OpenQuery"}, []string{"good", "bad"}, projects,
+			func(version *ProjectVersion) []int {
+				field, programmed := version.editMetrics(status)
+				return []int{
+					chartValue(field.TotalFiredWithOpenQuery) + chartValue(programmed.TotalFiredWithOpenQuery),
+					chartValue(field.TotalNotFiredWithOpenQuery) + chartValue(programmed.TotalNotFiredWithOpenQuery),
+				}
+			}),
+		newHTMLChart("Fired checks with change vs no change"+suffix, []string{"Change", "No Change"}, []string{"good", "bad"}, projects,
+			func(version *ProjectVersion) []int {
+				field, programmed := version.editMetrics(status)
+				return []int{
+					chartValue(field.TotalEditsFiredWithChange) + chartValue(programmed.TotalEditsFiredWithChange),
+					chartValue(field.TotalEditsFiredWithNoChange) + chartValue(programmed.TotalEditsFiredWithNoChange),
+				}
+			}),
+		newHTMLChart("Programmed vs field checks"+suffix, []string{"Programmed", "Field"}, []string{"prg", "fld"}, projects,
+			func(version *ProjectVersion) []int {
+				field, programmed := version.editMetrics(status)
+				return []int{programmed.TotalEdits, field.TotalEdits}
+			}),
+	}
+}
+
+// write the report as a single HTML file with no external dependencies
+func writeHTMLReport(raveURL RaveURL, workbook *xlsx.File, editStatus EditStatusFilter, now time.Time, out io.Writer) error {
+	report := htmlReport{
+		URL:         raveURL.URL(),
+		GeneratedAt: now.Format("2006-01-02 15:04"),
+		Tables:      newReportTables(workbook),
+	}
+	for _, status := range editStatus.statuses() {
+		report.Charts = append(report.Charts, newHTMLCharts(raveURL.Projects, status)...)
+	}
+	return htmlReportTemplate.Execute(out, report)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.URL}} edit check report</title>
+<style>
+body { font-family: Verdana, sans-serif; font-size: 13px; margin: 2em; color: #222; }
+h1 { font-size: 20px; }
+h2 { font-size: 16px; margin-top: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 3px 6px; white-space: nowrap; }
+th { background: #eee; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+tr:nth-child(even) td { background: #f8f8f8; }
+.chart { display: inline-block; vertical-align: top; margin: 0 2em 2em 0; }
+.legend span { display: inline-block; width: 10px; height: 10px; margin: 0 4px 0 12px; }
+.good { fill: #4c9a2a; background: #4c9a2a; }
+.bad { fill: #c0392b; background: #c0392b; }
+.prg { fill: #2c6fbb; background: #2c6fbb; }
+.fld { fill: #e69f00; background: #e69f00; }
+svg text { font-size: 11px; }
+</style>
+</head>
+<body>
+<h1>{{.URL}}</h1>
+<p>Generated {{.GeneratedAt}}</p>
+<h2>Last versions</h2>
+{{range .Charts}}
+<div class="chart">
+<h3>{{.Title}}</h3>
+<div class="legend">{{range .Legend}}<span class="{{.Class}}"></span>{{.Label}}{{end}}</div>
+<svg width="860" height="{{.Height}}" role="img" aria-label="{{.Title}}">
+{{range .Bars}}
+<g transform="translate(0,{{.Y}})">
+<text x="195" y="15" text-anchor="end">{{.Label}}</text>
+{{range .Segments}}<rect x="{{printf "%.1f" .X}}" y="2" width="{{printf "%.1f" .Width}}" height="18" class="{{.Class}}" transform="translate(200,0)"><title>{{.Label}}: {{.Value}}</title></rect>{{end}}
+<text x="805" y="15">{{.Total}}</text>
+</g>
+{{end}}
+</svg>
+</div>
+{{end}}
+{{range .Tables}}
+<h2>{{.Name}}</h2>
+<table class="sortable">
+<thead><tr>{{range .Headers}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+{{end}}
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, column) {
+    th.addEventListener("click", function () {
+      var ascending = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(function (other) { other.classList.remove("asc", "desc"); });
+      th.classList.add(ascending ? "asc" : "desc");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      var value = function (row) {
+        var text = row.cells[column] ? row.cells[column].textContent : "";
+        var number = parseFloat(text.replace(/[%,]/g, ""));
+        return isNaN(number) || !/^-?[\d.,]+%?$/.test(text.trim()) ? text.toLowerCase() : number;
+      };
+      rows.sort(function (a, b) {
+        var x = value(a), y = value(b);
+        if (typeof x !== typeof y) { x = String(x); y = String(y); }
+        return (x < y ? -1 : x > y ? 1 : 0) * (ascending ? 1 : -1);
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTMLReport(t *testing.T) {
+	for _, status := range []EditStatusFilter{ActiveChecks, AllChecks} {
+		dir, err := ioutil.TempDir("", "projector")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		job := defaultReportJob()
+		job.EditStatus = status.String()
+		workbook := fixtureWorkbook(t, "pharma.mdsol.com", job)
+		job.Format = FormatHTML.String()
+		raveURL := runFixtureReport(t, "pharma.mdsol.com", job, 1, dir)
+		content, err := ioutil.ReadFile(filepath.Join(dir, raveURL.URLPrefix()+".html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		html := string(content)
+		// 3 charts for each status and a table for each sheet of the workbook
+		if got := strings.Count(html, "<svg "); got != 3*len(status.statuses()) {
+			t.Errorf("%s: expected %d charts, got %d", status, 3*len(status.statuses()), got)
+		}
+		if got := strings.Count(html, "<table "); got != len(workbook.Sheets) {
+			t.Errorf("%s: expected %d tables, got %d", status, len(workbook.Sheets), got)
+		}
+		for _, sheet := range workbook.Sheets {
+			if !strings.Contains(html, "<h2>"+sheet.Name+"</h2>") {
+				t.Errorf("%s: no table for %s", status, sheet.Name)
+			}
+		}
+		// the field and programmed checks with OpenQuery of the last versions
+		for _, expected := range []string{
+			"<title>Fired with OpenQuery: 4</title>",
+			"<title>Not Fired with OpenQuery: 3</title>",
+			"<title>Fired with OpenQuery: 1</title>",
+			"<title>Not Fired with OpenQuery: 2</title>",
+		} {
+			if !strings.Contains(html, expected) {
+				t.Errorf("%s: expected %s", status, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// the version of the JSON report schema, incremented whenever a field is
+// removed, renamed or changes meaning
+const reportSchemaVersion = 1
+
+// JSONReport is the top level of the JSON report for a RaveURL
+type JSONReport struct {
+	SchemaVersion int       `json:"schema_version"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	Job           string    `json:"job,omitempty"`
+	// the edit checks the job reported on: active, inactive or all, the versions always
+	// have the metrics for both
+	EditStatus string            `json:"edit_status"`
+	RaveURL    JSONRaveURL       `json:"rave_url"`
+	Failures   []JSONLoadFailure `json:"failures"`
+}
+
+// JSONRaveURL is a Rave URL and its projects
+type JSONRaveURL struct {
+	URLID        int           `json:"url_id"`
+	URL          string        `json:"url"`
+	PreferredURL string        `json:"preferred_url"`
+	AlternateURL *string       `json:"alternate_url"`
+	Prefix       string        `json:"prefix"`
+	Projects     []JSONProject `json:"projects"`
+}
+
+// JSONProject is a project with its subject counts, versions and unused edits
+type JSONProject struct {
+	ProjectID                   int                  `json:"project_id"`
+	ProjectName                 string               `json:"project_name"`
+	SubjectCounts               JSONSubjectCounts    `json:"subject_counts"`
+	Versions                    []JSONProjectVersion `json:"versions"`
+	UnusedEditsWithOpenQuery    []JSONUnusedEdit     `json:"unused_edits_with_open_query"`
+	UnusedEditsWithoutOpenQuery []JSONUnusedEdit     `json:"unused_edits_without_open_query"`
+	// the names of the edit checks in any version
+	EditCheckNames []string `json:"edit_check_names"`
+}
+
+// JSONSubjectCounts are the subject counts for a project, null when not recorded
+type JSONSubjectCounts struct {
+	RefreshDate           *time.Time `json:"refresh_date"`
+	SubjectCount          int        `json:"subject_count"`
+	ScreeningCount        *int64     `json:"screening_count"`
+	ScreeningFailureCount *int64     `json:"screening_failure_count"`
+	EnrolledCount         *int64     `json:"enrolled_count"`
+	EarlyTerminatedCount  *int64     `json:"early_terminated_count"`
+	CompletedCount        *int64     `json:"completed_count"`
+	FollowUpCount         *int64     `json:"follow_up_count"`
+}
+
+// JSONProjectVersion is the edit check metrics for a CRF version
+type JSONProjectVersion struct {
+	CRFVersionID    int             `json:"crf_version_id"`
+	LastVersion     bool            `json:"last_version"`
+	ActiveEdits     int             `json:"active_edits"`
+	InactiveEdits   int             `json:"inactive_edits"`
+	FieldEdits      JSONEditMetrics `json:"field_edits"`
+	ProgrammedEdits JSONEditMetrics `json:"programmed_edits"`
+	// the same metrics over the inactive edits
+	InactiveFieldEdits      JSONEditMetrics `json:"inactive_field_edits"`
+	InactiveProgrammedEdits JSONEditMetrics `json:"inactive_programmed_edits"`
+}
+
+// JSONEditMetrics are the counts for the field or programmed edits of a version,
+// null when the version has no edits of the type; the percentages are 0 to 100
+type JSONEditMetrics struct {
+	TotalEdits                      *int64   `json:"total_edits"`
+	TotalEditsWithOpenQuery         *int64   `json:"total_edits_with_open_query"`
+	TotalQueries                    *int64   `json:"total_queries"`
+	TotalQueriesOpenQuery           *int64   `json:"total_queries_open_query"`
+	TotalOpenQueries                *int64   `json:"total_open_queries"`
+	TotalEditsFired                 *int64   `json:"total_edits_fired"`
+	TotalEditsNotFired              *int64   `json:"total_edits_not_fired"`
+	TotalFiredWithOpenQuery         *int64   `json:"total_fired_with_open_query"`
+	TotalNotFiredWithOpenQuery      *int64   `json:"total_not_fired_with_open_query"`
+	TotalEditsFiredWithChange       *int64   `json:"total_edits_fired_with_change"`
+	TotalEditsFiredWithNoChange     *int64   `json:"total_edits_fired_with_no_change"`
+	TotalQueriesWithChange          *int64   `json:"total_queries_with_change"`
+	TotalOpenEdits                  *int64   `json:"total_open_edits"`
+	PercentageFired                 *float64 `json:"percentage_fired"`
+	PercentageNotFired              *float64 `json:"percentage_not_fired"`
+	PercentageFiredWithOpenQuery    *float64 `json:"percentage_fired_with_open_query"`
+	PercentageNotFiredWithOpenQuery *float64 `json:"percentage_not_fired_with_open_query"`
+	PercentageChanged               *float64 `json:"percentage_changed"`
+	PercentageNotChanged            *float64 `json:"percentage_not_changed"`
+}
+
+// JSONUnusedEdit is an edit check that never fired
+type JSONUnusedEdit struct {
+	EditCheckName  string `json:"edit_check_name"`
+	FormOID        string `json:"form_oid"`
+	FieldOID       string `json:"field_oid"`
+	VariableOID    string `json:"variable_oid"`
+	UsageCount     int    `json:"usage_count"`
+	CustomFunction bool   `json:"custom_function"`
+}
+
+// JSONLoadFailure is a project skipped with -continue
+type JSONLoadFailure struct {
+	ProjectName string `json:"project_name"`
+	Error       string `json:"error"`
+}
+
+// the value, or nil when it is NULL
+func nullableInt(value sql.NullInt64) *int64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Int64
+}
+
+// the percentage, nil when either value is NULL and 0 when there is nothing to divide by
+func nullablePercentage(numerator, denominator sql.NullInt64) *float64 {
+	if !numerator.Valid || !denominator.Valid {
+		return nil
+	}
+	percentage := 0.0
+	if denominator.Int64 > 0 {
+		percentage = 100.0 * float64(numerator.Int64) / float64(denominator.Int64)
+	}
+	return &percentage
+}
+
+func newJSONEditMetrics(metric EditTypeMetric) JSONEditMetrics {
+	return JSONEditMetrics{
+		TotalEdits:                      nullableInt(metric.RawTotalEdits),
+		TotalEditsWithOpenQuery:         nullableInt(metric.RawTotalEditsWithOpenQuery),
+		TotalQueries:                    nullableInt(metric.RawTotalQueries),
+		TotalQueriesOpenQuery:           nullableInt(metric.RawTotalQueriesOpenQuery),
+		TotalOpenQueries:                nullableInt(metric.RawTotalOpenQueries),
+		TotalEditsFired:                 nullableInt(metric.RawTotalEditsFired),
+		TotalEditsNotFired:              nullableInt(metric.RawTotalEditsNotFired),
+		TotalFiredWithOpenQuery:         nullableInt(metric.RawTotalFiredWithOpenQuery),
+		TotalNotFiredWithOpenQuery:      nullableInt(metric.RawTotalNotFiredWithOpenQuery),
+		TotalEditsFiredWithChange:       nullableInt(metric.RawTotalEditsFiredWithChange),
+		TotalEditsFiredWithNoChange:     nullableInt(metric.RawTotalEditsFiredWithNoChange),
+		TotalQueriesWithChange:          nullableInt(metric.RawTotalQueriesWithChange),
+		TotalOpenEdits:                  nullableInt(metric.RawTotalOpenEdits),
+		PercentageFired:                 nullablePercentage(metric.RawTotalEditsFired, metric.RawTotalEdits),
+		PercentageNotFired:              nullablePercentage(metric.RawTotalEditsNotFired, metric.RawTotalEdits),
+		PercentageFiredWithOpenQuery:    nullablePercentage(metric.RawTotalFiredWithOpenQuery, metric.RawTotalEditsWithOpenQuery),
+		PercentageNotFiredWithOpenQuery: nullablePercentage(metric.RawTotalNotFiredWithOpenQuery, metric.RawTotalEditsWithOpenQuery),
+		PercentageChanged:               nullablePercentage(metric.RawTotalEditsFiredWithChange, metric.RawTotalEditsFired),
+		PercentageNotChanged:            nullablePercentage(metric.RawTotalEditsFiredWithNoChange, metric.RawTotalEditsFired),
+	}
+}
+
+func newJSONUnusedEdits(edits []*UnusedEdit) []JSONUnusedEdit {
+	unused := make([]JSONUnusedEdit, 0, len(edits))
+	for _, edit := range edits {
+		unused = append(unused, JSONUnusedEdit{
+			EditCheckName:  edit.EditCheckName,
+			FormOID:        edit.FormOID,
+			FieldOID:       edit.FieldOID,
+			VariableOID:    edit.VariableOID,
+			UsageCount:     edit.UsageCount,
+			CustomFunction: edit.CustomFunction,
+		})
+	}
+	return unused
+}
+
+func newJSONProject(project *Project) JSONProject {
+	counts := project.SubjectCount
+	jsonProject := JSONProject{
+		ProjectID:   project.ProjectID,
+		ProjectName: project.ProjectName,
+		SubjectCounts: JSONSubjectCounts{
+			SubjectCount:          counts.SubjectCount,
+			ScreeningCount:        nullableInt(counts.ScreeningCount),
+			ScreeningFailureCount: nullableInt(counts.ScreeningFailureCount),
+			EnrolledCount:         nullableInt(counts.EnrolledCount),
+			EarlyTerminatedCount:  nullableInt(counts.EarlyTerminatedCount),
+			CompletedCount:        nullableInt(counts.CompletedCount),
+			FollowUpCount:         nullableInt(counts.FollowUpCount),
+		},
+		Versions:                    make([]JSONProjectVersion, 0, len(project.Versions)),
+		UnusedEditsWithOpenQuery:    newJSONUnusedEdits(project.UnusedWithOpenQuery),
+		UnusedEditsWithoutOpenQuery: newJSONUnusedEdits(project.Unused),
+		EditCheckNames:              project.EditCheckNames,
+	}
+	if counts.RefreshDate.Valid {
+		refreshDate := counts.RefreshDate.Time
+		jsonProject.SubjectCounts.RefreshDate = &refreshDate
+	}
+	for _, version := range project.Versions {
+		jsonProject.Versions = append(jsonProject.Versions, JSONProjectVersion{
+			CRFVersionID:    version.CRFVersionID,
+			LastVersion:     version.LastVersion,
+			ActiveEdits:     version.EditStatus.ActiveEdits,
+			InactiveEdits:   version.EditStatus.InactiveEdits,
+			FieldEdits:      newJSONEditMetrics(version.FieldEditMetrics),
+			ProgrammedEdits: newJSONEditMetrics(version.ProgramEditMetrics),
+			// the same metrics over the inactive edits
+			InactiveFieldEdits:      newJSONEditMetrics(version.InactiveFieldEditMetrics),
+			InactiveProgrammedEdits: newJSONEditMetrics(version.InactiveProgramEditMetrics),
+		})
+	}
+	return jsonProject
+}
+
+// build the JSON report from the loaded RaveURL
+func newJSONReport(raveURL RaveURL, failures []LoadFailure, options ReportOptions, now time.Time) JSONReport {
+	report := JSONReport{
+		SchemaVersion: reportSchemaVersion,
+		GeneratedAt:   now.UTC(),
+		Job:           options.JobName,
+		EditStatus:    options.EditStatus.String(),
+		RaveURL: JSONRaveURL{
+			URLID:        raveURL.URLID,
+			URL:          raveURL.URL(),
+			PreferredURL: raveURL.PreferredURL,
+			Prefix:       raveURL.URLPrefix(),
+			Projects:     make([]JSONProject, 0, len(raveURL.Projects)),
+		},
+		Failures: make([]JSONLoadFailure, 0, len(failures)),
+	}
+	if raveURL.AlternateURL != "" {
+		alternateURL := raveURL.AlternateURL
+		report.RaveURL.AlternateURL = &alternateURL
+	}
+	for _, project := range raveURL.Projects {
+		report.RaveURL.Projects = append(report.RaveURL.Projects, newJSONProject(project))
+	}
+	for _, failure := range failures {
+		report.Failures = append(report.Failures, JSONLoadFailure{
+			ProjectName: failure.ProjectName,
+			Error:       failure.Err.Error(),
+		})
+	}
+	return report
+}
+
+// write the report as indented JSON
+func (report JSONReport) write(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
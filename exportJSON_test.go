@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNullablePercentage(t *testing.T) {
+	tests := []struct {
+		numerator, denominator sql.NullInt64
+		expected               *float64
+	}{
+		{sql.NullInt64{Int64: 1, Valid: true}, sql.NullInt64{Int64: 4, Valid: true}, floatPointer(25)},
+		// nothing to divide by
+		{sql.NullInt64{Int64: 0, Valid: true}, sql.NullInt64{Int64: 0, Valid: true}, floatPointer(0)},
+		{sql.NullInt64{}, sql.NullInt64{Int64: 4, Valid: true}, nil},
+		{sql.NullInt64{Int64: 1, Valid: true}, sql.NullInt64{}, nil},
+	}
+	for _, test := range tests {
+		got := nullablePercentage(test.numerator, test.denominator)
+		if (got == nil) != (test.expected == nil) || (got != nil && *got != *test.expected) {
+			t.Errorf("%v / %v: expected %v, got %v", test.numerator, test.denominator, test.expected, got)
+		}
+	}
+}
+
+func floatPointer(value float64) *float64 {
+	return &value
+}
+
+func TestJSONReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "projector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	job := defaultReportJob()
+	job.Format = FormatJSON.String()
+	raveURL := runFixtureReport(t, "pharma.mdsol.com", job, 1, dir)
+	fileName := filepath.Join(dir, raveURL.URLPrefix()+".json")
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := loadJSONReport(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SchemaVersion != reportSchemaVersion || report.EditStatus != "active" {
+		t.Errorf("unexpected schema version %d and edit status %q", report.SchemaVersion, report.EditStatus)
+	}
+	// the loaded report writes out the same
+	var written bytes.Buffer
+	if err := report.write(&written); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written.Bytes(), content) {
+		t.Errorf("expected the report to round trip, got\n%s\nfrom\n%s", written.String(), content)
+	}
+
+	projects := report.RaveURL.Projects
+	if len(projects) != 2 || projects[0].ProjectName != "Alpha" || projects[1].ProjectName != "Beta" {
+		t.Fatalf("expected the Alpha and Beta projects, got %+v", projects)
+	}
+	if len(projects[0].EditCheckNames) != 8 {
+		t.Errorf("expected the 8 check names of Alpha, got %v", projects[0].EditCheckNames)
+	}
+	if projects[1].SubjectCounts.EarlyTerminatedCount != nil {
+		t.Error("expected an unrecorded subject count to be null")
+	}
+	alpha := projects[0].Versions[1]
+	if alpha.CRFVersionID != 101 || alpha.FieldEdits.TotalEdits == nil || *alpha.FieldEdits.TotalEdits != 4 {
+		t.Errorf("expected 4 field edits for version 101, got %+v", alpha.FieldEdits)
+	}
+	// Alpha has no inactive edits in the last version, so nothing was recorded
+	if alpha.InactiveFieldEdits.TotalEdits != nil || alpha.InactiveFieldEdits.PercentageFired != nil {
+		t.Errorf("expected the unrecorded metrics to be null, got %+v", alpha.InactiveFieldEdits)
+	}
+	// Beta's inactive check has no OpenQuery, so there is nothing to divide by
+	beta := projects[1].Versions[0].InactiveProgrammedEdits
+	if beta.PercentageFiredWithOpenQuery == nil || *beta.PercentageFiredWithOpenQuery != 0 {
+		t.Errorf("expected 0 with nothing to divide by, got %v", beta.PercentageFiredWithOpenQuery)
+	}
+	if !strings.Contains(string(content), `"total_edits": null`) {
+		t.Error("expected the unrecorded metrics to be written as null")
+	}
+}
+
+func TestJSONReportEmptyLists(t *testing.T) {
+	projects := []*Project{{ProjectID: 10, ProjectName: "Alpha"}}
+	// a project without any checks
+	if err := loadEditCheckNames(context.Background(), &MemoryStore{}, 1, projects); err != nil {
+		t.Fatal(err)
+	}
+	raveURL := RaveURL{URLID: 1, PreferredURL: "pharma.mdsol.com", Projects: projects}
+	report := newJSONReport(raveURL, nil, ReportOptions{}, time.Now())
+	content, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`"edit_check_names":[]`,
+		`"failures":[]`,
+		`"versions":[]`,
+		`"unused_edits_with_open_query":[]`,
+		`"alternate_url":null`,
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("expected %s in %s", expected, content)
+		}
+	}
+}
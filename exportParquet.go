@@ -0,0 +1,290 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// a row of subject_counts.parquet
+type parquetSubjectCount struct {
+	URL                   string `parquet:"name=url, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ProjectID             int64  `parquet:"name=project_id, type=INT64"`
+	ProjectName           string `parquet:"name=project_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RefreshDate           *int64 `parquet:"name=refresh_date, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"`
+	SubjectCount          int64  `parquet:"name=subject_count, type=INT64"`
+	ScreeningCount        *int64 `parquet:"name=screening_count, type=INT64, repetitiontype=OPTIONAL"`
+	ScreeningFailureCount *int64 `parquet:"name=screening_failure_count, type=INT64, repetitiontype=OPTIONAL"`
+	EnrolledCount         *int64 `parquet:"name=enrolled_count, type=INT64, repetitiontype=OPTIONAL"`
+	EarlyTerminatedCount  *int64 `parquet:"name=early_terminated_count, type=INT64, repetitiontype=OPTIONAL"`
+	CompletedCount        *int64 `parquet:"name=completed_count, type=INT64, repetitiontype=OPTIONAL"`
+	FollowUpCount         *int64 `parquet:"name=follow_up_count, type=INT64, repetitiontype=OPTIONAL"`
+}
+
+// a row of version_metrics.parquet, one per version, check type (field or programmed) and
+// edit status (active or inactive)
+type parquetVersionMetric struct {
+	URL                             string   `parquet:"name=url, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ProjectID                       int64    `parquet:"name=project_id, type=INT64"`
+	ProjectName                     string   `parquet:"name=project_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	CRFVersionID                    int64    `parquet:"name=crf_version_id, type=INT64"`
+	LastVersion                     bool     `parquet:"name=last_version, type=BOOLEAN"`
+	ActiveEdits                     int64    `parquet:"name=active_edits, type=INT64"`
+	InactiveEdits                   int64    `parquet:"name=inactive_edits, type=INT64"`
+	CheckType                       string   `parquet:"name=check_type, type=BYTE_ARRAY, convertedtype=UTF8"`
+	EditStatus                      string   `parquet:"name=edit_status, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TotalEdits                      *int64   `parquet:"name=total_edits, type=INT64, repetitiontype=OPTIONAL"`
+	TotalEditsWithOpenQuery         *int64   `parquet:"name=total_edits_with_open_query, type=INT64, repetitiontype=OPTIONAL"`
+	TotalQueries                    *int64   `parquet:"name=total_queries, type=INT64, repetitiontype=OPTIONAL"`
+	TotalQueriesOpenQuery           *int64   `parquet:"name=total_queries_open_query, type=INT64, repetitiontype=OPTIONAL"`
+	TotalOpenQueries                *int64   `parquet:"name=total_open_queries, type=INT64, repetitiontype=OPTIONAL"`
+	TotalEditsFired                 *int64   `parquet:"name=total_edits_fired, type=INT64, repetitiontype=OPTIONAL"`
+	TotalEditsNotFired              *int64   `parquet:"name=total_edits_not_fired, type=INT64, repetitiontype=OPTIONAL"`
+	TotalFiredWithOpenQuery         *int64   `parquet:"name=total_fired_with_open_query, type=INT64, repetitiontype=OPTIONAL"`
+	TotalNotFiredWithOpenQuery      *int64   `parquet:"name=total_not_fired_with_open_query, type=INT64, repetitiontype=OPTIONAL"`
+	TotalEditsFiredWithChange       *int64   `parquet:"name=total_edits_fired_with_change, type=INT64, repetitiontype=OPTIONAL"`
+	TotalEditsFiredWithNoChange     *int64   `parquet:"name=total_edits_fired_with_no_change, type=INT64, repetitiontype=OPTIONAL"`
+	TotalQueriesWithChange          *int64   `parquet:"name=total_queries_with_change, type=INT64, repetitiontype=OPTIONAL"`
+	TotalOpenEdits                  *int64   `parquet:"name=total_open_edits, type=INT64, repetitiontype=OPTIONAL"`
+	PercentageFired                 *float64 `parquet:"name=percentage_fired, type=DOUBLE, repetitiontype=OPTIONAL"`
+	PercentageNotFired              *float64 `parquet:"name=percentage_not_fired, type=DOUBLE, repetitiontype=OPTIONAL"`
+	PercentageFiredWithOpenQuery    *float64 `parquet:"name=percentage_fired_with_open_query, type=DOUBLE, repetitiontype=OPTIONAL"`
+	PercentageNotFiredWithOpenQuery *float64 `parquet:"name=percentage_not_fired_with_open_query, type=DOUBLE, repetitiontype=OPTIONAL"`
+	PercentageChanged               *float64 `parquet:"name=percentage_changed, type=DOUBLE, repetitiontype=OPTIONAL"`
+	PercentageNotChanged            *float64 `parquet:"name=percentage_not_changed, type=DOUBLE, repetitiontype=OPTIONAL"`
+}
+
+// a row of unused_edits.parquet
+type parquetUnusedEdit struct {
+	URL            string `parquet:"name=url, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ProjectID      int64  `parquet:"name=project_id, type=INT64"`
+	ProjectName    string `parquet:"name=project_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	EditCheckName  string `parquet:"name=edit_check_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	FormOID        string `parquet:"name=form_oid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	FieldOID       string `parquet:"name=field_oid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	VariableOID    string `parquet:"name=variable_oid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	UsageCount     int64  `parquet:"name=usage_count, type=INT64"`
+	OpenQuery      bool   `parquet:"name=open_query, type=BOOLEAN"`
+	CustomFunction bool   `parquet:"name=custom_function, type=BOOLEAN"`
+}
+
+// a row of summary_counts.parquet, the sum or average for a cohort
+type parquetSummaryCount struct {
+	URL                        string  `parquet:"name=url, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Cohort                     string  `parquet:"name=cohort, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Criteria                   string  `parquet:"name=criteria, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Aggregate                  string  `parquet:"name=aggregate, type=BYTE_ARRAY, convertedtype=UTF8"`
+	EditStatus                 string  `parquet:"name=edit_status, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RecordCount                int64   `parquet:"name=record_count, type=INT64"`
+	SubjectCount               float64 `parquet:"name=subject_count, type=DOUBLE"`
+	TotalEdits                 float64 `parquet:"name=total_edits, type=DOUBLE"`
+	TotalFldEdits              float64 `parquet:"name=total_fld_edits, type=DOUBLE"`
+	TotalFldEditsFired         float64 `parquet:"name=total_fld_edits_fired, type=DOUBLE"`
+	TotalFldEditsUnfired       float64 `parquet:"name=total_fld_edits_unfired, type=DOUBLE"`
+	TotalFldEditsOpen          float64 `parquet:"name=total_fld_edits_open, type=DOUBLE"`
+	TotalFldWithChange         float64 `parquet:"name=total_fld_with_change, type=DOUBLE"`
+	TotalFldWithNoChange       float64 `parquet:"name=total_fld_with_no_change, type=DOUBLE"`
+	TotalPrgEdits              float64 `parquet:"name=total_prg_edits, type=DOUBLE"`
+	TotalPrgEditsWithOpenQuery float64 `parquet:"name=total_prg_edits_with_open_query, type=DOUBLE"`
+	TotalPrgEditsFired         float64 `parquet:"name=total_prg_edits_fired, type=DOUBLE"`
+	TotalPrgEditsUnfired       float64 `parquet:"name=total_prg_edits_unfired, type=DOUBLE"`
+	TotalPrgEditsOpen          float64 `parquet:"name=total_prg_edits_open, type=DOUBLE"`
+	TotalPrgWithChange         float64 `parquet:"name=total_prg_with_change, type=DOUBLE"`
+	TotalPrgWithNoChange       float64 `parquet:"name=total_prg_with_no_change, type=DOUBLE"`
+}
+
+func newParquetVersionMetric(url string, project *Project, version *ProjectVersion, checkType string, status EditStatusFilter, metric *EditTypeMetric) parquetVersionMetric {
+	return parquetVersionMetric{
+		URL:                             url,
+		ProjectID:                       int64(project.ProjectID),
+		ProjectName:                     project.ProjectName,
+		CRFVersionID:                    int64(version.CRFVersionID),
+		LastVersion:                     version.LastVersion,
+		ActiveEdits:                     int64(version.EditStatus.ActiveEdits),
+		InactiveEdits:                   int64(version.EditStatus.InactiveEdits),
+		CheckType:                       checkType,
+		EditStatus:                      status.String(),
+		TotalEdits:                      nullableInt(metric.RawTotalEdits),
+		TotalEditsWithOpenQuery:         nullableInt(metric.RawTotalEditsWithOpenQuery),
+		TotalQueries:                    nullableInt(metric.RawTotalQueries),
+		TotalQueriesOpenQuery:           nullableInt(metric.RawTotalQueriesOpenQuery),
+		TotalOpenQueries:                nullableInt(metric.RawTotalOpenQueries),
+		TotalEditsFired:                 nullableInt(metric.RawTotalEditsFired),
+		TotalEditsNotFired:              nullableInt(metric.RawTotalEditsNotFired),
+		TotalFiredWithOpenQuery:         nullableInt(metric.RawTotalFiredWithOpenQuery),
+		TotalNotFiredWithOpenQuery:      nullableInt(metric.RawTotalNotFiredWithOpenQuery),
+		TotalEditsFiredWithChange:       nullableInt(metric.RawTotalEditsFiredWithChange),
+		TotalEditsFiredWithNoChange:     nullableInt(metric.RawTotalEditsFiredWithNoChange),
+		TotalQueriesWithChange:          nullableInt(metric.RawTotalQueriesWithChange),
+		TotalOpenEdits:                  nullableInt(metric.RawTotalOpenEdits),
+		PercentageFired:                 nullablePercentage(metric.RawTotalEditsFired, metric.RawTotalEdits),
+		PercentageNotFired:              nullablePercentage(metric.RawTotalEditsNotFired, metric.RawTotalEdits),
+		PercentageFiredWithOpenQuery:    nullablePercentage(metric.RawTotalFiredWithOpenQuery, metric.RawTotalEditsWithOpenQuery),
+		PercentageNotFiredWithOpenQuery: nullablePercentage(metric.RawTotalNotFiredWithOpenQuery, metric.RawTotalEditsWithOpenQuery),
+		PercentageChanged:               nullablePercentage(metric.RawTotalEditsFiredWithChange, metric.RawTotalEditsFired),
+		PercentageNotChanged:            nullablePercentage(metric.RawTotalEditsFiredWithNoChange, metric.RawTotalEditsFired),
+	}
+}
+
+func newParquetSummaryCounts(url string, cohort CohortCounts, status EditStatusFilter) []parquetSummaryCount {
+	if cohort.Counts.RecordCount == 0 {
+		return nil
+	}
+	sum := cohort.Counts
+	avg := sum.getAverageCounts()
+	return []parquetSummaryCount{
+		{
+			URL:                        url,
+			Cohort:                     cohort.Name,
+			Criteria:                   cohort.Criteria,
+			Aggregate:                  "sum",
+			EditStatus:                 status.String(),
+			RecordCount:                int64(sum.RecordCount),
+			SubjectCount:               float64(sum.SubjectCount),
+			TotalEdits:                 float64(sum.TotalEdits),
+			TotalFldEdits:              float64(sum.TotalFldEdits),
+			TotalFldEditsFired:         float64(sum.TotalFldEditsFired),
+			TotalFldEditsUnfired:       float64(sum.TotalFldEditsUnfired),
+			TotalFldEditsOpen:          float64(sum.TotalFldEditsOpen),
+			TotalFldWithChange:         float64(sum.TotalFldWithChange),
+			TotalFldWithNoChange:       float64(sum.TotalFldWithNoChange),
+			TotalPrgEdits:              float64(sum.TotalPrgEdits),
+			TotalPrgEditsWithOpenQuery: float64(sum.TotalPrgEditsWithOpenQuery),
+			TotalPrgEditsFired:         float64(sum.TotalPrgEditsFired),
+			TotalPrgEditsUnfired:       float64(sum.TotalPrgEditsUnfired),
+			TotalPrgEditsOpen:          float64(sum.TotalPrgEditsOpen),
+			TotalPrgWithChange:         float64(sum.TotalPrgWithChange),
+			TotalPrgWithNoChange:       float64(sum.TotalPrgWithNoChange),
+		},
+		{
+			URL:                        url,
+			Cohort:                     cohort.Name,
+			Criteria:                   cohort.Criteria,
+			Aggregate:                  "average",
+			EditStatus:                 status.String(),
+			RecordCount:                int64(avg.RecordCount),
+			SubjectCount:               avg.SubjectCount,
+			TotalEdits:                 avg.TotalEdits,
+			TotalFldEdits:              avg.TotalFldEdits,
+			TotalFldEditsFired:         avg.TotalFldEditsFired,
+			TotalFldEditsUnfired:       avg.TotalFldEditsUnfired,
+			TotalFldEditsOpen:          avg.TotalFldEditsOpen,
+			TotalFldWithChange:         avg.TotalFldWithChange,
+			TotalFldWithNoChange:       avg.TotalFldWithNoChange,
+			TotalPrgEdits:              avg.TotalPrgEdits,
+			TotalPrgEditsWithOpenQuery: avg.TotalPrgEditsWithOpenQuery,
+			TotalPrgEditsFired:         avg.TotalPrgEditsFired,
+			TotalPrgEditsUnfired:       avg.TotalPrgEditsUnfired,
+			TotalPrgEditsOpen:          avg.TotalPrgEditsOpen,
+			TotalPrgWithChange:         avg.TotalPrgWithChange,
+			TotalPrgWithNoChange:       avg.TotalPrgWithNoChange,
+		},
+	}
+}
+
+// write the rows to a Parquet file, the rows must all be of the type of the schema
+func writeParquetFile(fileName string, schema interface{}, rows []interface{}) (err error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	parquetWriter, err := writer.NewParquetWriterFromWriter(file, schema, 1)
+	if err != nil {
+		return err
+	}
+	parquetWriter.CompressionType = parquet.CompressionCodec_SNAPPY
+	for _, row := range rows {
+		if err = parquetWriter.Write(row); err != nil {
+			return err
+		}
+	}
+	return parquetWriter.WriteStop()
+}
+
+// write the subject counts, version metrics, unused edits and summary counts over the
+// reported edit statuses as Parquet files in the directory
+func writeParquetReport(raveURL RaveURL, cohorts []Cohort, editStatus EditStatusFilter, dir string) error {
+	url := raveURL.URL()
+	var subjectCounts, versionMetrics, unusedEdits, summaryCounts []interface{}
+	for _, project := range raveURL.Projects {
+		counts := project.SubjectCount
+		subjectCount := parquetSubjectCount{
+			URL:                   url,
+			ProjectID:             int64(project.ProjectID),
+			ProjectName:           project.ProjectName,
+			SubjectCount:          int64(counts.SubjectCount),
+			ScreeningCount:        nullableInt(counts.ScreeningCount),
+			ScreeningFailureCount: nullableInt(counts.ScreeningFailureCount),
+			EnrolledCount:         nullableInt(counts.EnrolledCount),
+			EarlyTerminatedCount:  nullableInt(counts.EarlyTerminatedCount),
+			CompletedCount:        nullableInt(counts.CompletedCount),
+			FollowUpCount:         nullableInt(counts.FollowUpCount),
+		}
+		if counts.RefreshDate.Valid {
+			subjectCount.RefreshDate = nullableInt(sql.NullInt64{
+				Int64: counts.RefreshDate.Time.UnixNano() / 1e6,
+				Valid: true,
+			})
+		}
+		subjectCounts = append(subjectCounts, subjectCount)
+		for _, version := range project.Versions {
+			for _, status := range editStatus.statuses() {
+				fieldMetrics, programMetrics := version.editMetrics(status)
+				versionMetrics = append(versionMetrics,
+					newParquetVersionMetric(url, project, version, "field", status, fieldMetrics),
+					newParquetVersionMetric(url, project, version, "programmed", status, programMetrics))
+			}
+		}
+		for _, outcome := range []EditCheckOutcome{OpenQuery, WithoutOpenQuery} {
+			edits := project.Unused
+			if outcome == OpenQuery {
+				edits = project.UnusedWithOpenQuery
+			}
+			for _, edit := range edits {
+				unusedEdits = append(unusedEdits, parquetUnusedEdit{
+					URL:            url,
+					ProjectID:      int64(project.ProjectID),
+					ProjectName:    project.ProjectName,
+					EditCheckName:  edit.EditCheckName,
+					FormOID:        edit.FormOID,
+					FieldOID:       edit.FieldOID,
+					VariableOID:    edit.VariableOID,
+					UsageCount:     int64(edit.UsageCount),
+					OpenQuery:      outcome == OpenQuery,
+					CustomFunction: edit.CustomFunction,
+				})
+			}
+		}
+	}
+	for _, status := range editStatus.statuses() {
+		for _, cohort := range summarizeCohorts(raveURL.Projects, cohorts, status) {
+			for _, row := range newParquetSummaryCounts(url, cohort, status) {
+				summaryCounts = append(summaryCounts, row)
+			}
+		}
+	}
+	files := []struct {
+		name   string
+		schema interface{}
+		rows   []interface{}
+	}{
+		{"subject_counts.parquet", new(parquetSubjectCount), subjectCounts},
+		{"version_metrics.parquet", new(parquetVersionMetric), versionMetrics},
+		{"unused_edits.parquet", new(parquetUnusedEdit), unusedEdits},
+		{"summary_counts.parquet", new(parquetSummaryCount), summaryCounts},
+	}
+	for _, file := range files {
+		if err := writeParquetFile(filepath.Join(dir, file.name), file.schema, file.rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// a local file for the Parquet reader
+type parquetTestFile struct {
+	*os.File
+}
+
+// the reader opens the file again for each column, an empty name being the same file
+func (f parquetTestFile) Open(name string) (source.ParquetFile, error) {
+	if name == "" {
+		name = f.Name()
+	}
+	file, err := os.Open(name)
+	return parquetTestFile{file}, err
+}
+
+func (f parquetTestFile) Create(name string) (source.ParquetFile, error) {
+	file, err := os.Create(name)
+	return parquetTestFile{file}, err
+}
+
+// read the rows of a Parquet file into the slice pointed to by rows, returning the columns
+// by their names in the file
+func readParquetFile(t *testing.T, fileName string, schema interface{}, rows interface{}) map[string]*parquet.SchemaElement {
+	t.Helper()
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	parquetReader, err := reader.NewParquetReader(parquetTestFile{file}, schema, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parquetReader.ReadStop()
+	if err := parquetReader.Read(rows); err != nil {
+		t.Fatal(err)
+	}
+	// the reader renames the footer schema to the struct fields
+	columns := make(map[string]*parquet.SchemaElement)
+	for idx, info := range parquetReader.SchemaHandler.Infos {
+		columns[info.ExName] = parquetReader.Footer.Schema[idx]
+	}
+	return columns
+}
+
+func TestParquetReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "projector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	job := defaultReportJob()
+	job.Format = FormatParquet.String()
+	job.EditStatus = AllChecks.String()
+	raveURL := runFixtureReport(t, "pharma.mdsol.com", job, 1, dir)
+	reportDir := filepath.Join(dir, raveURL.URLPrefix())
+
+	subjectCounts := make([]parquetSubjectCount, 2)
+	columns := readParquetFile(t, filepath.Join(reportDir, "subject_counts.parquet"), new(parquetSubjectCount), &subjectCounts)
+	refreshDate, ok := columns["refresh_date"]
+	if !ok {
+		t.Fatal("no refresh_date column")
+	}
+	if refreshDate.ConvertedType == nil || *refreshDate.ConvertedType != parquet.ConvertedType_TIMESTAMP_MILLIS ||
+		*refreshDate.RepetitionType != parquet.FieldRepetitionType_OPTIONAL {
+		t.Errorf("expected an optional TIMESTAMP_MILLIS refresh_date, got %v", refreshDate)
+	}
+	alpha := subjectCounts[0]
+	expectedDate := time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC).UnixNano() / 1e6
+	if alpha.ProjectName != "Alpha" || alpha.RefreshDate == nil || *alpha.RefreshDate != expectedDate {
+		t.Errorf("expected Alpha refreshed at %d, got %+v", expectedDate, alpha)
+	}
+	beta := subjectCounts[1]
+	if beta.ProjectName != "Beta" || beta.EarlyTerminatedCount != nil || beta.CompletedCount == nil || *beta.CompletedCount != 0 {
+		t.Errorf("expected Beta with no early terminated count and 0 completed, got %+v", beta)
+	}
+
+	// 3 versions with the field and programmed metrics for both statuses
+	versionMetrics := make([]parquetVersionMetric, 12)
+	readParquetFile(t, filepath.Join(reportDir, "version_metrics.parquet"), new(parquetVersionMetric), &versionMetrics)
+	var unrecorded int
+	for _, row := range versionMetrics {
+		if row.ProjectName == "Alpha" && row.CRFVersionID == 101 && row.EditStatus == "inactive" {
+			unrecorded++
+			if row.TotalEdits != nil || row.PercentageFired != nil {
+				t.Errorf("expected the unrecorded %s metrics to be null, got %+v", row.CheckType, row)
+			}
+		}
+		if row.ProjectName == "Beta" && row.CheckType == "programmed" && row.EditStatus == "inactive" {
+			if row.TotalEdits == nil || *row.TotalEdits != 1 ||
+				row.PercentageFiredWithOpenQuery == nil || *row.PercentageFiredWithOpenQuery != 0 {
+				t.Errorf("expected Beta's inactive check with nothing to divide by, got %+v", row)
+			}
+		}
+	}
+	if unrecorded != 2 {
+		t.Errorf("expected the field and programmed inactive metrics for Alpha 101, got %d", unrecorded)
+	}
+
+	unusedEdits := make([]parquetUnusedEdit, 7)
+	readParquetFile(t, filepath.Join(reportDir, "unused_edits.parquet"), new(parquetUnusedEdit), &unusedEdits)
+	var withOpenQuery int
+	for _, row := range unusedEdits {
+		if row.OpenQuery {
+			withOpenQuery++
+		}
+	}
+	if withOpenQuery != 5 {
+		t.Errorf("expected 5 unused edits with OpenQuery, got %d", withOpenQuery)
+	}
+
+	// the sum and average of each cohort with projects, for both statuses
+	var expectedSummaries int
+	for _, status := range AllChecks.statuses() {
+		for _, cohort := range summarizeCohorts(loadFixtureURL(t, "pharma.mdsol.com", job).Projects, fixtureOptions(t, job, 1).Cohorts, status) {
+			expectedSummaries += len(newParquetSummaryCounts("", cohort, status))
+		}
+	}
+	summaryCounts := make([]parquetSummaryCount, expectedSummaries)
+	readParquetFile(t, filepath.Join(reportDir, "summary_counts.parquet"), new(parquetSummaryCount), &summaryCounts)
+	if expectedSummaries == 0 || summaryCounts[0].Aggregate != "sum" || summaryCounts[0].EditStatus != "active" {
+		t.Errorf("expected the active sums first, got %+v", summaryCounts)
+	}
+
+	// the files hold exactly the rows expected
+	files := []struct {
+		name     string
+		schema   interface{}
+		expected int64
+	}{
+		{"subject_counts.parquet", new(parquetSubjectCount), 2},
+		{"version_metrics.parquet", new(parquetVersionMetric), 12},
+		{"unused_edits.parquet", new(parquetUnusedEdit), 7},
+		{"summary_counts.parquet", new(parquetSummaryCount), int64(expectedSummaries)},
+	}
+	for _, file := range files {
+		parquetFile, err := os.Open(filepath.Join(reportDir, file.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parquetReader, err := reader.NewParquetReader(parquetTestFile{parquetFile}, file.schema, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := parquetReader.GetNumRows(); got != file.expected {
+			t.Errorf("%s: expected %d rows, got %d", file.name, file.expected, got)
+		}
+		parquetReader.ReadStop()
+		parquetFile.Close()
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tealeg/xlsx"
+)
+
+// a sheet of the workbook with the values as shown in Excel
+type reportTable struct {
+	Name    string
+	Headers []string
+	Rows    [][]string
+}
+
+// read the sheets of the workbook as tables, all of them when no names are given
+func newReportTables(workbook *xlsx.File, names ...string) []reportTable {
+	var tables []reportTable
+	for _, sheet := range workbook.Sheets {
+		if len(names) > 0 && !containsString(names, sheet.Name) {
+			continue
+		}
+		table := reportTable{Name: sheet.Name}
+		for rowIdx, row := range sheet.Rows {
+			var values []string
+			for _, cell := range row.Cells {
+				value, err := cell.FormattedValue()
+				if err != nil {
+					value = cell.Value
+				}
+				values = append(values, value)
+			}
+			if rowIdx == 0 {
+				table.Headers = values
+			} else {
+				table.Rows = append(table.Rows, values)
+			}
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// is the value in the list
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// write the table as aligned columns of text
+func (table reportTable) writeText(out io.Writer) error {
+	fmt.Fprintf(out, "%s\n\n", table.Name)
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	underline := make([]string, len(table.Headers))
+	for idx, header := range table.Headers {
+		underline[idx] = strings.Repeat("-", len(header))
+	}
+	fmt.Fprintln(writer, strings.Join(table.Headers, "\t"))
+	fmt.Fprintln(writer, strings.Join(underline, "\t"))
+	for _, row := range table.Rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
+
+// escape a value for a Markdown table cell
+func markdownCell(value string) string {
+	return strings.Replace(value, "|", `\|`, -1)
+}
+
+// write the table as a Markdown table
+func (table reportTable) writeMarkdown(out io.Writer) error {
+	line := func(values []string) {
+		cells := make([]string, len(values))
+		for idx, value := range values {
+			cells[idx] = markdownCell(value)
+		}
+		fmt.Fprintf(out, "| %s |\n", strings.Join(cells, " | "))
+	}
+	fmt.Fprintf(out, "### %s\n\n", table.Name)
+	line(table.Headers)
+	separator := make([]string, len(table.Headers))
+	for idx := range separator {
+		separator[idx] = "---"
+	}
+	line(separator)
+	for _, row := range table.Rows {
+		line(row)
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
+
+// print the Subject Counts, last version and Summary Counts sheets for a URL, for the
+// edit statuses that were reported
+func writeTableReport(raveURL RaveURL, workbook *xlsx.File, markdown bool, out io.Writer) error {
+	names := []string{"Subject Counts"}
+	for _, status := range AllChecks.statuses() {
+		names = append(names, lastVersionSheetName(raveURL.URLPrefix(), status), summaryCountsSheetName(status))
+	}
+	tables := newReportTables(workbook, names...)
+	if markdown {
+		fmt.Fprintf(out, "## %s\n\n", raveURL.URL())
+	} else {
+		fmt.Fprintf(out, "%s\n%s\n\n", raveURL.URL(), strings.Repeat("=", len(raveURL.URL())))
+	}
+	for _, table := range tables {
+		var err error
+		if markdown {
+			err = table.writeMarkdown(out)
+		} else {
+			err = table.writeText(out)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
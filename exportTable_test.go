@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tealeg/xlsx"
+)
+
+func TestMarkdownCell(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"Alpha", "Alpha"},
+		{"OpenQuery|CustomFunction", `OpenQuery\|CustomFunction`},
+		{"|", `\|`},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := markdownCell(test.value); got != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.value, test.expected, got)
+		}
+	}
+}
+
+func TestWriteMarkdownEscapesPipes(t *testing.T) {
+	table := reportTable{
+		Name:    "Checks",
+		Headers: []string{"Edit Check", "Actions"},
+		Rows:    [][]string{{"AE_ONSET_BEFORE_CONSENT", "OpenQuery|CustomFunction"}},
+	}
+	var out bytes.Buffer
+	if err := table.writeMarkdown(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected := "### Checks\n\n" +
+		"| Edit Check | Actions |\n" +
+		"| --- | --- |\n" +
+		"| AE_ONSET_BEFORE_CONSENT | OpenQuery\\|CustomFunction |\n\n"
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+// the sheets printed for a URL, in the order of the workbook
+func tableSheetNames(raveURL RaveURL, workbook *xlsx.File) []string {
+	printed := []string{"Subject Counts"}
+	for _, status := range AllChecks.statuses() {
+		printed = append(printed, lastVersionSheetName(raveURL.URLPrefix(), status), summaryCountsSheetName(status))
+	}
+	var names []string
+	for _, sheet := range workbook.Sheets {
+		if containsString(printed, sheet.Name) {
+			names = append(names, sheet.Name)
+		}
+	}
+	return names
+}
+
+func TestWriteTableReportMarkdown(t *testing.T) {
+	job := defaultReportJob()
+	job.EditStatus = AllChecks.String()
+	workbook := fixtureWorkbook(t, "pharma.mdsol.com", job)
+	raveURL := loadFixtureURL(t, "pharma.mdsol.com", job)
+	var out bytes.Buffer
+	if err := writeTableReport(raveURL, workbook, true, &out); err != nil {
+		t.Fatal(err)
+	}
+	// read the tables back by their headings
+	tables := make(map[string][][]string)
+	var name string
+	for _, line := range strings.Split(out.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "### "):
+			name = strings.TrimPrefix(line, "### ")
+		case strings.HasPrefix(line, "| --- "):
+		case strings.HasPrefix(line, "| "):
+			cells := strings.Split(strings.TrimSuffix(strings.TrimPrefix(line, "| "), " |"), " | ")
+			for idx, cell := range cells {
+				cells[idx] = strings.Replace(cell, `\|`, "|", -1)
+			}
+			tables[name] = append(tables[name], cells)
+		}
+	}
+	if !strings.HasPrefix(out.String(), "## "+raveURL.URL()+"\n") {
+		t.Errorf("expected the URL as the heading, got %q", strings.SplitN(out.String(), "\n", 2)[0])
+	}
+	names := tableSheetNames(raveURL, workbook)
+	if len(names) != 5 || len(tables) != len(names) {
+		t.Errorf("expected the %d tables %v, got %d", len(names), names, len(tables))
+	}
+	for _, name := range names {
+		expected := sheetRows(t, workbook, name)
+		got, ok := tables[name]
+		if !ok {
+			t.Errorf("no table for %s", name)
+			continue
+		}
+		if len(got) != len(expected) {
+			t.Errorf("%s: expected %d rows, got %d", name, len(expected), len(got))
+			continue
+		}
+		for idx := range expected {
+			if !equalStrings(got[idx], expected[idx]) {
+				t.Errorf("%s row %d: expected %v, got %v", name, idx, expected[idx], got[idx])
+			}
+		}
+	}
+}
+
+func TestWriteTableReportText(t *testing.T) {
+	job := defaultReportJob()
+	job.EditStatus = AllChecks.String()
+	workbook := fixtureWorkbook(t, "pharma.mdsol.com", job)
+	raveURL := loadFixtureURL(t, "pharma.mdsol.com", job)
+	var out bytes.Buffer
+	if err := writeTableReport(raveURL, workbook, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	// each table is its name, a blank line, the headers, the underline, the rows and a blank line
+	lines := strings.Split(out.String(), "\n")
+	if lines[0] != raveURL.URL() || lines[1] != strings.Repeat("=", len(raveURL.URL())) {
+		t.Errorf("expected the underlined URL, got %q", lines[:2])
+	}
+	lineIdx := 3
+	for _, name := range tableSheetNames(raveURL, workbook) {
+		if lineIdx >= len(lines) || lines[lineIdx] != name {
+			t.Fatalf("expected the %s table at line %d", name, lineIdx)
+		}
+		rows := sheetRows(t, workbook, name)
+		tableLines := lines[lineIdx+2 : lineIdx+2+len(rows)+1]
+		if !strings.HasPrefix(tableLines[1], "---") {
+			t.Errorf("%s: expected the headers underlined, got %q", name, tableLines[1])
+		}
+		// the values are in the columns in order
+		for rowIdx, row := range rows {
+			line := tableLines[rowIdx]
+			if rowIdx > 0 {
+				line = tableLines[rowIdx+1]
+			}
+			position := 0
+			for _, value := range row {
+				found := strings.Index(line[position:], value)
+				if found < 0 {
+					t.Errorf("%s row %d: expected %q in %q", name, rowIdx, value, line)
+					break
+				}
+				position += found + len(value)
+			}
+		}
+		lineIdx += 2 + len(rows) + 2
+	}
+}
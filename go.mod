@@ -6,4 +6,5 @@ require (
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/lib/pq v1.3.0
 	github.com/tealeg/xlsx v1.0.5
+	github.com/xitongsys/parquet-go v1.6.2
 )
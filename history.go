@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// HistoryStore keeps a snapshot of every run in a separate Postgres schema
+type HistoryStore struct {
+	db     *sqlx.DB
+	schema string
+}
+
+// HistorySettings are the options for the snapshot store, disabled without a schema
+type HistorySettings struct {
+	// the schema holding the snapshot tables
+	Schema string `json:"schema"`
+	// the connection for the snapshots, the report database when empty
+	DSN string `json:"dsn"`
+}
+
+// the raw metric columns, named as in EditTypeMetric
+var historyMetricColumns = []string{
+	"total_edits",
+	"total_edits_with_openquery",
+	"total_queries",
+	"total_queries_open_query",
+	"total_open_queries",
+	"total_edits_fired",
+	"total_edits_not_fired",
+	"total_edits_fired_with_openquery",
+	"total_edits_not_fired_with_openquery",
+	"total_edits_fired_with_change",
+	"total_edits_fired_without_change",
+	"total_changes_from_edits",
+	"total_open_edits",
+}
+
+// the subject count columns, named as in SubjectCount
+var historySubjectCountColumns = []string{
+	"url_id",
+	"project_id",
+	"project_name",
+	"refresh_date",
+	"subject_count",
+	"screening_subject_count",
+	"screening_failure_subject_count",
+	"enrolled_subject_count",
+	"early_terminated_subject_count",
+	"completed_subject_count",
+	"follow_up_subject_count",
+}
+
+var historyVersionColumns = []string{
+	"project_id",
+	"crf_version_id",
+	"check_type",
+	"is_active",
+	"refresh_date",
+	"last_version",
+	"active_count",
+	"inactive_count",
+}
+
+var historyUnusedEditColumns = []string{
+	"project_id",
+	"project_name",
+	"refresh_date",
+	"edit_check_name",
+	"form_oids",
+	"field_oids",
+	"variable_oids",
+	"total_count",
+	"with_open_query",
+	"custom_function",
+}
+
+// a row of the version_metric table
+type historyVersionMetric struct {
+	RunID       int64       `db:"run_id"`
+	RefreshDate pq.NullTime `db:"refresh_date"`
+	LastVersion bool        `db:"last_version"`
+	EditStatusCounts
+	VersionEditTypeMetric
+}
+
+// a row of the subject_count table
+type historySubjectCount struct {
+	RunID int64 `db:"run_id"`
+	SubjectCount
+}
+
+// a row of the unused_edit table
+type historyUnusedEdit struct {
+	RunID         int64       `db:"run_id"`
+	RefreshDate   pq.NullTime `db:"refresh_date"`
+	WithOpenQuery bool        `db:"with_open_query"`
+	UnusedEdit
+}
+
+// a row of the edit_check_name table
+type historyEditCheckName struct {
+	RunID int64 `db:"run_id"`
+	ProjectEditCheckName
+}
+
+// create a HistoryStore for the database connection
+func newHistoryStore(db *sqlx.DB, schema string) *HistoryStore {
+	return &HistoryStore{db: db, schema: schema}
+}
+
+// connect to the snapshot store, reusing the report database unless it has its own
+// connection, and create the tables
+func openHistoryStore(ctx context.Context, settings HistorySettings, reportDB *sqlx.DB) (*HistoryStore, error) {
+	db := reportDB
+	if settings.DSN != "" {
+		var err error
+		if db, err = sqlx.Open("postgres", settings.DSN); err != nil {
+			return nil, err
+		}
+	}
+	if db == nil {
+		return nil, fmt.Errorf("a fixture has no database, supply the snapshot connection")
+	}
+	history := newHistoryStore(db, settings.Schema)
+	if err := history.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// the qualified name of a snapshot table
+func (h *HistoryStore) table(name string) string {
+	return pq.QuoteIdentifier(h.schema) + "." + name
+}
+
+// create the schema and tables if they don't exist
+func (h *HistoryStore) ensureSchema(ctx context.Context) error {
+	metricColumns := strings.Join(historyMetricColumns, " bigint,\n\t\t") + " bigint"
+	ddl := `CREATE SCHEMA IF NOT EXISTS ` + pq.QuoteIdentifier(h.schema) + `;
+	CREATE TABLE IF NOT EXISTS ` + h.table("run") + ` (
+		run_id bigserial PRIMARY KEY,
+		run_at timestamptz NOT NULL,
+		job text NOT NULL DEFAULT '',
+		url_id integer NOT NULL,
+		url text NOT NULL,
+		edit_status text NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS run_url_id_run_at ON ` + h.table("run") + ` (url_id, run_at);
+	CREATE TABLE IF NOT EXISTS ` + h.table("subject_count") + ` (
+		run_id bigint NOT NULL REFERENCES ` + h.table("run") + ` ON DELETE CASCADE,
+		url_id integer NOT NULL,
+		project_id integer NOT NULL,
+		project_name text NOT NULL,
+		refresh_date timestamp,
+		subject_count integer NOT NULL,
+		screening_subject_count bigint,
+		screening_failure_subject_count bigint,
+		enrolled_subject_count bigint,
+		early_terminated_subject_count bigint,
+		completed_subject_count bigint,
+		follow_up_subject_count bigint,
+		PRIMARY KEY (run_id, project_id)
+	);
+	CREATE TABLE IF NOT EXISTS ` + h.table("version_metric") + ` (
+		run_id bigint NOT NULL REFERENCES ` + h.table("run") + ` ON DELETE CASCADE,
+		project_id integer NOT NULL,
+		crf_version_id integer NOT NULL,
+		check_type smallint NOT NULL,
+		is_active smallint NOT NULL,
+		refresh_date timestamp,
+		last_version boolean NOT NULL,
+		active_count integer NOT NULL,
+		inactive_count integer NOT NULL,
+		` + metricColumns + `,
+		PRIMARY KEY (run_id, project_id, crf_version_id, check_type, is_active)
+	);
+	CREATE TABLE IF NOT EXISTS ` + h.table("unused_edit") + ` (
+		run_id bigint NOT NULL REFERENCES ` + h.table("run") + ` ON DELETE CASCADE,
+		project_id integer NOT NULL,
+		project_name text NOT NULL,
+		refresh_date timestamp,
+		edit_check_name text NOT NULL,
+		form_oids text,
+		field_oids text,
+		variable_oids text,
+		total_count integer NOT NULL,
+		with_open_query boolean NOT NULL,
+		custom_function boolean NOT NULL,
+		PRIMARY KEY (run_id, project_id, edit_check_name, with_open_query)
+	);
+	CREATE TABLE IF NOT EXISTS ` + h.table("edit_check_name") + ` (
+		run_id bigint NOT NULL REFERENCES ` + h.table("run") + ` ON DELETE CASCADE,
+		project_id integer NOT NULL,
+		edit_check_name text NOT NULL,
+		PRIMARY KEY (run_id, project_id, edit_check_name)
+	);`
+	_, err := h.db.ExecContext(ctx, ddl)
+	return err
+}
+
+// an INSERT with named parameters for the columns
+func (h *HistoryStore) insertStatement(table string, columns []string) string {
+	return fmt.Sprintf("INSERT INTO %s (run_id, %s) VALUES (:run_id, :%s)",
+		h.table(table), strings.Join(columns, ", "), strings.Join(columns, ", :"))
+}
+
+// the rows of a run, one slice per snapshot table
+type historySnapshot struct {
+	subjectCounts  []historySubjectCount
+	versionMetrics []historyVersionMetric
+	unusedEdits    []historyUnusedEdit
+	editCheckNames []historyEditCheckName
+}
+
+// the rows saving the loaded RaveURL as the run
+func newHistorySnapshot(raveURL RaveURL, runID int64) (snapshot historySnapshot) {
+	for _, project := range raveURL.Projects {
+		counts := project.SubjectCount
+		counts.URLID = raveURL.URLID
+		counts.ProjectID = project.ProjectID
+		counts.ProjectName = project.ProjectName
+		snapshot.subjectCounts = append(snapshot.subjectCounts, historySubjectCount{RunID: runID, SubjectCount: counts})
+		for _, version := range project.Versions {
+			// both sets of metrics, whatever the job reported on
+			for _, status := range AllChecks.statuses() {
+				fieldMetrics, programMetrics := version.editMetrics(status)
+				for _, checkType := range []EditCheckClass{Field, Programmed} {
+					metric := fieldMetrics
+					if checkType == Programmed {
+						metric = programMetrics
+					}
+					row := historyVersionMetric{
+						RunID:            runID,
+						RefreshDate:      counts.RefreshDate,
+						LastVersion:      version.LastVersion,
+						EditStatusCounts: version.EditStatus,
+						VersionEditTypeMetric: VersionEditTypeMetric{
+							ProjectID:      project.ProjectID,
+							CRFVersionID:   version.CRFVersionID,
+							CheckType:      checkType,
+							IsActive:       boolToInt(status == ActiveChecks),
+							EditTypeMetric: *metric,
+						},
+					}
+					snapshot.versionMetrics = append(snapshot.versionMetrics, row)
+				}
+			}
+		}
+		for _, outcome := range []EditCheckOutcome{OpenQuery, WithoutOpenQuery} {
+			edits := project.Unused
+			if outcome == OpenQuery {
+				edits = project.UnusedWithOpenQuery
+			}
+			for _, edit := range edits {
+				row := historyUnusedEdit{
+					RunID:         runID,
+					RefreshDate:   counts.RefreshDate,
+					WithOpenQuery: outcome == OpenQuery,
+					UnusedEdit:    *edit,
+				}
+				row.ProjectID = project.ProjectID
+				row.ProjectName = project.ProjectName
+				snapshot.unusedEdits = append(snapshot.unusedEdits, row)
+			}
+		}
+		for _, name := range project.EditCheckNames {
+			row := historyEditCheckName{
+				RunID:                runID,
+				ProjectEditCheckName: ProjectEditCheckName{ProjectID: project.ProjectID, EditCheckName: name},
+			}
+			snapshot.editCheckNames = append(snapshot.editCheckNames, row)
+		}
+	}
+	return snapshot
+}
+
+// store the loaded RaveURL as a run, returning the run id
+func (h *HistoryStore) saveSnapshot(ctx context.Context, raveURL RaveURL, runAt time.Time, options ReportOptions) (runID int64, err error) {
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := `INSERT INTO ` + h.table("run") + ` (run_at, job, url_id, url, edit_status) VALUES ($1, $2, $3, $4, $5) RETURNING run_id`
+	err = tx.QueryRowxContext(ctx, q, runAt, options.JobName, raveURL.URLID, raveURL.URL(), options.EditStatus.String()).Scan(&runID)
+	if err != nil {
+		return 0, err
+	}
+	subjectCounts, err := tx.PrepareNamedContext(ctx, h.insertStatement("subject_count", historySubjectCountColumns))
+	if err != nil {
+		return 0, err
+	}
+	defer subjectCounts.Close()
+	versionMetrics, err := tx.PrepareNamedContext(ctx,
+		h.insertStatement("version_metric", append(historyVersionColumns, historyMetricColumns...)))
+	if err != nil {
+		return 0, err
+	}
+	defer versionMetrics.Close()
+	unusedEdits, err := tx.PrepareNamedContext(ctx, h.insertStatement("unused_edit", historyUnusedEditColumns))
+	if err != nil {
+		return 0, err
+	}
+	defer unusedEdits.Close()
+	editCheckNames, err := tx.PrepareNamedContext(ctx, h.insertStatement("edit_check_name", []string{"project_id", "edit_check_name"}))
+	if err != nil {
+		return 0, err
+	}
+	defer editCheckNames.Close()
+	snapshot := newHistorySnapshot(raveURL, runID)
+	for _, row := range snapshot.subjectCounts {
+		if _, err = subjectCounts.ExecContext(ctx, row); err != nil {
+			return 0, fmt.Errorf("saving subject counts for %s: %w", row.ProjectName, err)
+		}
+	}
+	for _, row := range snapshot.versionMetrics {
+		if _, err = versionMetrics.ExecContext(ctx, row); err != nil {
+			return 0, fmt.Errorf("saving metrics for project %d: %w", row.ProjectID, err)
+		}
+	}
+	for _, row := range snapshot.unusedEdits {
+		if _, err = unusedEdits.ExecContext(ctx, row); err != nil {
+			return 0, fmt.Errorf("saving unused edits for %s: %w", row.ProjectName, err)
+		}
+	}
+	for _, row := range snapshot.editCheckNames {
+		if _, err = editCheckNames.ExecContext(ctx, row); err != nil {
+			return 0, fmt.Errorf("saving edit check names for project %d: %w", row.ProjectID, err)
+		}
+	}
+	err = tx.Commit()
+	return runID, err
+}
+
+// a row of the run table
+type historyRun struct {
+	RunAt      time.Time `db:"run_at"`
+	Job        string    `db:"job"`
+	URLID      int       `db:"url_id"`
+	URL        string    `db:"url"`
+	EditStatus string    `db:"edit_status"`
+}
+
+// load a stored run as a JSON report, so it can be compared with an export
+func (h *HistoryStore) loadSnapshot(ctx context.Context, runID int64) (JSONReport, error) {
+	var run historyRun
+	q := `SELECT run_at, job, url_id, url, edit_status FROM ` + h.table("run") + ` WHERE run_id = $1`
+	if err := h.db.GetContext(ctx, &run, q, runID); err != nil {
+		if err == sql.ErrNoRows {
+			return JSONReport{}, fmt.Errorf("no run %d in the snapshot store", runID)
+		}
+		return JSONReport{}, err
+	}
+	var snapshot historySnapshot
+	q = `SELECT ` + strings.Join(historySubjectCountColumns, ", ") + ` FROM ` + h.table("subject_count") +
+		` WHERE run_id = $1 ORDER BY project_name`
+	if err := h.db.SelectContext(ctx, &snapshot.subjectCounts, q, runID); err != nil {
+		return JSONReport{}, fmt.Errorf("loading subject counts: %w", err)
+	}
+	q = `SELECT ` + strings.Join(append(historyVersionColumns, historyMetricColumns...), ", ") +
+		` FROM ` + h.table("version_metric") + ` WHERE run_id = $1`
+	if err := h.db.SelectContext(ctx, &snapshot.versionMetrics, q, runID); err != nil {
+		return JSONReport{}, fmt.Errorf("loading metrics: %w", err)
+	}
+	q = `SELECT ` + strings.Join(historyUnusedEditColumns, ", ") + ` FROM ` + h.table("unused_edit") +
+		` WHERE run_id = $1 ORDER BY edit_check_name`
+	if err := h.db.SelectContext(ctx, &snapshot.unusedEdits, q, runID); err != nil {
+		return JSONReport{}, fmt.Errorf("loading unused edits: %w", err)
+	}
+	q = `SELECT project_id, edit_check_name FROM ` + h.table("edit_check_name") +
+		` WHERE run_id = $1 ORDER BY project_id, edit_check_name`
+	if err := h.db.SelectContext(ctx, &snapshot.editCheckNames, q, runID); err != nil {
+		return JSONReport{}, fmt.Errorf("loading edit check names: %w", err)
+	}
+	return rebuildSnapshot(run, snapshot)
+}
+
+// rebuild the project tree of a stored run as a JSON report
+func rebuildSnapshot(run historyRun, snapshot historySnapshot) (JSONReport, error) {
+	raveURL := RaveURL{PreferredURL: run.URL, URLID: run.URLID}
+	projects := make(map[int]*Project)
+	for _, row := range snapshot.subjectCounts {
+		counts := row.SubjectCount
+		project := &Project{
+			URLID:        run.URLID,
+			ProjectID:    counts.ProjectID,
+			ProjectName:  counts.ProjectName,
+			SubjectCount: counts,
+			// every snapshot records the names, even when there are none
+			EditCheckNames: []string{},
+		}
+		projects[project.ProjectID] = project
+		raveURL.Projects = append(raveURL.Projects, project)
+	}
+	versions := make(map[[2]int]*ProjectVersion)
+	for _, metric := range snapshot.versionMetrics {
+		project, ok := projects[metric.ProjectID]
+		if !ok {
+			continue
+		}
+		key := [2]int{metric.ProjectID, metric.CRFVersionID}
+		version, ok := versions[key]
+		if !ok {
+			version = &ProjectVersion{
+				ProjectID:    metric.ProjectID,
+				CRFVersionID: metric.CRFVersionID,
+				LastVersion:  metric.LastVersion,
+				EditStatus:   metric.EditStatusCounts,
+			}
+			versions[key] = version
+			project.Versions = append(project.Versions, version)
+		}
+		version.setEditMetrics(metric.CheckType, metric.IsActive, metric.EditTypeMetric)
+	}
+	for _, version := range versions {
+		version.calculateMetrics()
+	}
+	for _, edit := range snapshot.unusedEdits {
+		project, ok := projects[edit.ProjectID]
+		if !ok {
+			continue
+		}
+		unused := edit.UnusedEdit
+		if edit.WithOpenQuery {
+			project.UnusedWithOpenQuery = append(project.UnusedWithOpenQuery, &unused)
+		} else {
+			project.Unused = append(project.Unused, &unused)
+		}
+	}
+	for _, name := range snapshot.editCheckNames {
+		if project, ok := projects[name.ProjectID]; ok {
+			project.EditCheckNames = append(project.EditCheckNames, name.EditCheckName)
+		}
+	}
+	for _, project := range raveURL.Projects {
+		project.Versions = orderVersions(project.Versions)
+	}
+	editStatus, err := parseEditStatusFilter(run.EditStatus)
+	if err != nil {
+		return JSONReport{}, err
+	}
+	options := ReportOptions{JobName: run.Job, EditStatus: editStatus}
+	return newJSONReport(raveURL, nil, options, run.RunAt), nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestInsertStatement(t *testing.T) {
+	history := newHistoryStore(nil, "projector history")
+	expected := `INSERT INTO "projector history".edit_check_name (run_id, project_id, edit_check_name) ` +
+		`VALUES (:run_id, :project_id, :edit_check_name)`
+	if got := history.insertStatement("edit_check_name", []string{"project_id", "edit_check_name"}); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestHistorySnapshotRows(t *testing.T) {
+	job := defaultReportJob()
+	job.Format = FormatJSON.String()
+	raveURL := loadFixtureURL(t, "pharma.mdsol.com", job)
+	snapshot := newHistorySnapshot(raveURL, 7)
+	// 2 projects, 3 versions with the field and programmed metrics for both statuses
+	// and the unused edits and check names of each
+	tests := []struct {
+		table    string
+		rows     int
+		expected int
+	}{
+		{"subject_count", len(snapshot.subjectCounts), 2},
+		{"version_metric", len(snapshot.versionMetrics), 12},
+		{"unused_edit", len(snapshot.unusedEdits), 7},
+		{"edit_check_name", len(snapshot.editCheckNames), 12},
+	}
+	for _, test := range tests {
+		if test.rows != test.expected {
+			t.Errorf("%s: expected %d rows, got %d", test.table, test.expected, test.rows)
+		}
+	}
+	for _, row := range snapshot.versionMetrics {
+		if row.RunID != 7 {
+			t.Errorf("expected run 7, got %d", row.RunID)
+		}
+	}
+	for _, row := range snapshot.subjectCounts {
+		if row.RunID != 7 || row.URLID != raveURL.URLID {
+			t.Errorf("expected run 7 for URL %d, got %+v", raveURL.URLID, row)
+		}
+	}
+}
+
+func TestRebuildSnapshot(t *testing.T) {
+	job := defaultReportJob()
+	job.Format = FormatJSON.String()
+	raveURL := loadFixtureURL(t, "pharma.mdsol.com", job)
+	runAt := time.Date(2020, 2, 1, 6, 0, 0, 0, time.UTC)
+	run := historyRun{RunAt: runAt, Job: "nightly", URLID: raveURL.URLID, URL: raveURL.URL(), EditStatus: "all"}
+	report, err := rebuildSnapshot(run, newHistorySnapshot(raveURL, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the stored run reads back as the export of the same load
+	expected := newJSONReport(raveURL, nil, ReportOptions{JobName: "nightly", EditStatus: AllChecks}, runAt)
+	var got, want bytes.Buffer
+	if err := report.write(&got); err != nil {
+		t.Fatal(err)
+	}
+	if err := expected.write(&want); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("expected the snapshot\n%s\ngot\n%s", want.String(), got.String())
+	}
+
+	run.EditStatus = "some"
+	if _, err := rebuildSnapshot(run, historySnapshot{}); err == nil {
+		t.Error("expected an unknown edit status to be refused")
+	}
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/tealeg/xlsx"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -24,8 +29,8 @@ func (i *arrayFlags) Set(value string) error {
 	return nil
 }
 
-func getURLs(db *sqlx.DB) {
-	urls, err := listURLs(db)
+func getURLs(ctx context.Context, store Store) {
+	urls, err := store.listURLs(ctx)
 	if err != nil {
 		log.Fatal("Unable to list URLs: ", err)
 	}
@@ -35,131 +40,533 @@ func getURLs(db *sqlx.DB) {
 }
 
 // load the queries, versions, etc
-func loadProject(db *sqlx.DB, urlID int, project *Project) {
+func loadProject(ctx context.Context, store Store, urlID int, project *Project) error {
 	// load in the UnusedQueries
-	project.loadUnusedQueries(db)
+	if err := project.loadUnusedQueries(ctx, store); err != nil {
+		return fmt.Errorf("loading unused edits: %w", err)
+	}
 	// get the versions
-	projectVersions := getProjectVersions(db, project.ProjectID)
-	for _, projectVersion := range projectVersions {
-		projectVersion.getActivityCounts(db)
-		projectVersion.getMetrics(db)
+	projectVersions, err := store.getProjectVersions(ctx, project.ProjectID)
+	if err != nil {
+		return fmt.Errorf("loading versions: %w", err)
 	}
 	// ensure the versions are ordered appropriately
 	project.Versions = orderVersions(projectVersions)
+	return nil
 }
 
 // fluff out a project definition
-func expandProject(db *sqlx.DB, raveURL RaveURL, project *Project, subjectCounts []SubjectCount) {
+func expandProject(ctx context.Context, store Store, raveURL RaveURL, project *Project, subjectCounts []SubjectCount) error {
 	log.Println("Expanding ", project.ProjectName)
-	// TODO: Concurrency
-	loadProject(db, raveURL.URLID, project)
+	if err := loadProject(ctx, store, raveURL.URLID, project); err != nil {
+		return err
+	}
 	for _, counts := range subjectCounts {
 		if counts.ProjectID == project.ProjectID {
 			project.SubjectCount = counts
 		}
 	}
+	return nil
+}
+
+// expand the projects using a bounded pool of workers, the errors are returned
+// in the same order as the projects regardless of the order they complete in
+func expandProjects(ctx context.Context, store Store, raveURL RaveURL, projects []*Project, subjectCounts []SubjectCount, workers int) []error {
+	errs := make([]error, len(projects))
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				// drain the remaining projects once cancelled
+				if err := ctx.Err(); err != nil {
+					errs[idx] = err
+					continue
+				}
+				errs[idx] = expandProject(ctx, store, raveURL, projects[idx], subjectCounts)
+			}
+		}()
+	}
+	for idx := range projects {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
+
+// ReportOptions controls how a RaveURL report is generated
+type ReportOptions struct {
+	// the job the report belongs to
+	JobName string
+	// skip the projects that fail to load
+	ContinueOnError bool
+	// number of projects to expand concurrently
+	Workers int
+	// the Rave domain, removed for the URL prefix
+	Domain string
+	// the cohorts for the Summary Counts sheet
+	Cohorts []Cohort
+	// the sheets to write
+	Sheets map[string]bool
+	// where the workbooks are written
+	OutputDir string
+	// the workbook name, see filenamePlaceholders
+	FilenameTemplate string
+	// what to do when the workbook already exists
+	OnCollision CollisionPolicy
+	// write the workbook or a directory of CSV files
+	Format ReportFormat
+	// where the snapshot of each run is stored, nil for none
+	History *HistoryStore
+	// how the edit checks are classified
+	Rules *ClassificationRules
+	// the metric sets to report, active, inactive or both
+	EditStatus EditStatusFilter
+	// the number of checks in each top and bottom of the Check Ranking sheet
+	RankingSize int
+}
+
+// is the sheet enabled for the report
+func (options ReportOptions) sheetEnabled(name string) bool {
+	return options.Sheets[name]
 }
 
 // process a RaveURL dataset
-func processRaveURL(db *sqlx.DB, raveURL RaveURL) {
-	workbook := xlsx.NewFile()
+func processRaveURL(ctx context.Context, store Store, raveURL RaveURL, options ReportOptions) error {
+	raveURL, failures, err := loadRaveURL(ctx, store, raveURL, options)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := writeRaveURLReport(raveURL, failures, options, now); err != nil {
+		return err
+	}
+	// only once the report is written, so a failed run isn't kept
+	if options.History != nil {
+		runID, err := options.History.saveSnapshot(ctx, raveURL, now, options)
+		if err != nil {
+			return fmt.Errorf("saving snapshot: %w", err)
+		}
+		log.Println("Saved snapshot", runID, "for", raveURL.URL())
+	}
+	return nil
+}
+
+// load the projects of a RaveURL with what the report needs, the projects skipped with
+// -continue are returned as failures
+func loadRaveURL(ctx context.Context, store Store, raveURL RaveURL, options ReportOptions) (RaveURL, []LoadFailure, error) {
 	//if !doesPatternMatch(urlPattern, dbConn) {
 	//	log.Println("No matching URLs for", urlPattern)
 	//	continue
 	//}
 	log.Println("Processing Rave URL ", raveURL.URL())
 	// get the projects
-	projects := getProjects(db, raveURL.URLID)
+	projects, err := store.getProjects(ctx, raveURL.URLID)
+	if err != nil {
+		return raveURL, nil, err
+	}
 	log.Println("Loaded", len(projects), "Projects")
 	// sort the projects
 	projects = orderProjects(projects)
 	// load the subjectCounts
-	subjectCounts := getSubjectCounts(db, raveURL.URLID)
+	subjectCounts, err := store.getSubjectCounts(ctx, raveURL.URLID)
+	if err != nil {
+		return raveURL, nil, err
+	}
 	// Get the project versions
-	for _, project := range projects {
-		// can we parallelise this?
-		expandProject(db, raveURL, project, subjectCounts)
+	errs := expandProjects(ctx, store, raveURL, projects, subjectCounts, options.Workers)
+	var failures []LoadFailure
+	var loaded []*Project
+	for idx, project := range projects {
+		if err := errs[idx]; err != nil {
+			if !options.ContinueOnError || ctx.Err() != nil {
+				return raveURL, nil, fmt.Errorf("project %s: %w", project.ProjectName, err)
+			}
+			log.Println("Skipping", project.ProjectName, ":", err)
+			failures = append(failures, LoadFailure{
+				URL:         raveURL.URL(),
+				ProjectName: project.ProjectName,
+				Err:         err,
+			})
+			continue
+		}
+		loaded = append(loaded, project)
+	}
+	projects = loaded
+	// attach the metrics to the versions
+	if err := loadVersionMetrics(ctx, store, raveURL.URLID, projects); err != nil {
+		return raveURL, nil, err
+	}
+	// the individual edit checks, only for the sheets comparing them
+	needsEditChecks := options.sheetEnabled(SheetVersionDiff) || options.sheetEnabled(SheetEditCheckDetail) ||
+		options.sheetEnabled(SheetCheckRanking)
+	if needsEditChecks && options.Format != FormatJSON && options.Format != FormatParquet {
+		if err := loadEditChecks(ctx, store, raveURL.URLID, projects); err != nil {
+			return raveURL, nil, err
+		}
+	}
+	// the check names let diff tell a check that fired from one that was removed
+	if options.Format == FormatJSON || options.History != nil {
+		if err := loadEditCheckNames(ctx, store, raveURL.URLID, projects); err != nil {
+			return raveURL, nil, err
+		}
+	}
+	raveURL.Projects = projects
+	return raveURL, failures, nil
+}
+
+// write the report for the loaded RaveURL in the format of the options
+func writeRaveURLReport(raveURL RaveURL, failures []LoadFailure, options ReportOptions, now time.Time) error {
+	workbook := xlsx.NewFile()
+	filename := options.reportFileName(raveURL, now)
+	if options.Format == FormatJSON {
+		// the whole tree rather than the sheets
+		report := newJSONReport(raveURL, failures, options, now)
+		saved, err := writeFileAtomic(replaceExtension(filename, ".json"), options.OnCollision, report.write)
+		if err != nil {
+			return err
+		}
+		log.Println("Saved", saved)
+		return nil
 	}
+	if options.Format == FormatParquet {
+		// typed tables, in a directory named for the workbook
+		saved, err := writeDirAtomic(replaceExtension(filename, ""), options.OnCollision, func(dir string) error {
+			return writeParquetReport(raveURL, options.Cohorts, options.EditStatus, dir)
+		})
+		if err != nil {
+			return err
+		}
+		log.Println("Saved", saved)
+		return nil
+	}
+	projects := raveURL.Projects
 	// WRITE OUT THE SUBJECT COUNTS
-	writeSubjectCount(raveURL.URL(), projects, workbook)
+	if options.sheetEnabled(SheetSubjectCounts) {
+		if err := writeSubjectCount(raveURL.URL(), projects, workbook); err != nil {
+			return err
+		}
+	}
 	// Process useless edits project by project
 	for _, project := range projects {
-		// OpenQuery
-		writeUselessEdits(project.ProjectName, project.UnusedWithOpenQuery, OpenQuery, workbook)
-		// Not OpenQuery
-		writeUselessEdits(project.ProjectName, project.Unused, WithoutOpenQuery, workbook)
+		if options.sheetEnabled(SheetUnusedEdits) {
+			// OpenQuery
+			if err := writeUselessEdits(project.ProjectName, project.UnusedWithOpenQuery, OpenQuery, options.Rules, workbook); err != nil {
+				return err
+			}
+			// Not OpenQuery
+			if err := writeUselessEdits(project.ProjectName, project.Unused, WithoutOpenQuery, options.Rules, workbook); err != nil {
+				return err
+			}
+		}
 		// versions
-		writeStudyMetricsForProject(raveURL.URLPrefix(), project, workbook)
+		if options.sheetEnabled(SheetVersions) {
+			for _, status := range options.EditStatus.statuses() {
+				if err := writeStudyMetricsForProject(raveURL.URLPrefix(), project, status, workbook); err != nil {
+					return err
+				}
+			}
+		}
 		// last version
-		writeLastStudyMetricsForProject(raveURL.URLPrefix(), project, workbook)
-
+		if options.sheetEnabled(SheetLastVersion) {
+			for _, status := range options.EditStatus.statuses() {
+				if err := writeLastStudyMetricsForProject(raveURL.URLPrefix(), project, status, workbook); err != nil {
+					return err
+				}
+			}
+		}
+		// edit check changes between versions
+		if options.sheetEnabled(SheetVersionDiff) {
+			if err := writeVersionDiffForProject(project, workbook); err != nil {
+				return err
+			}
+		}
+	}
+	// every check of the last versions
+	if options.sheetEnabled(SheetEditCheckDetail) {
+		if err := writeEditCheckDetail(projects, workbook); err != nil {
+			return err
+		}
+	}
+	// the noisiest and most valuable checks
+	if options.sheetEnabled(SheetCheckRanking) {
+		if err := writeCheckRanking(projects, options.EditStatus, options.RankingSize, workbook); err != nil {
+			return err
+		}
 	}
 	// aggregated counts
-	writeSummaryCounts(projects, workbook)
+	if options.sheetEnabled(SheetSummaryCounts) {
+		for _, status := range options.EditStatus.statuses() {
+			if err := writeSummaryCounts(projects, options.Cohorts, status, workbook); err != nil {
+				return err
+			}
+		}
+	}
+	// skipped projects
+	if err := writeLoadFailures(failures, workbook); err != nil {
+		return err
+	}
+	if len(workbook.Sheets) == 0 {
+		log.Println("No sheets to write for", raveURL.URL())
+		return nil
+	}
 
+	// print rather than save
+	if options.Format == FormatTable || options.Format == FormatMarkdown {
+		return writeTableReport(raveURL, workbook, options.Format == FormatMarkdown, os.Stdout)
+	}
 	// write to disk
-	filename := fmt.Sprintf("%s_%s.xlsx", raveURL.URLPrefix(), time.Now().Format("2006-01-02"))
+	var saved string
+	var err error
+	switch options.Format {
+	case FormatCSV:
+		// one CSV per sheet, in a directory named for the workbook
+		dirName := replaceExtension(filename, "")
+		saved, err = writeDirAtomic(dirName, options.OnCollision, func(dir string) error {
+			return writeWorkbookCSV(workbook, dir)
+		})
+	case FormatHTML:
+		saved, err = writeFileAtomic(replaceExtension(filename, ".html"), options.OnCollision, func(out io.Writer) error {
+			return writeHTMLReport(raveURL, workbook, options.EditStatus, now, out)
+		})
+	default:
+		saved, err = writeFileAtomic(filename, options.OnCollision, workbook.Write)
+	}
+	if err != nil {
+		return err
+	}
+	log.Println("Saved", saved)
+	return nil
+}
 
-	err := workbook.Save(filename)
+// run a report job against each of the matching URLs
+func runJob(ctx context.Context, store Store, job ReportJob, workers int, history *HistoryStore, rules *ClassificationRules) error {
+	patterns, exclusions, err := job.matchers()
+	if err != nil {
+		return err
+	}
+	options, err := job.reportOptions(workers)
 	if err != nil {
-		log.Println("Error: ", err)
+		return err
+	}
+	options.History = history
+	options.Rules = rules
+	// each matching URL is processed once
+	matchingURLs, err := resolveURLs(ctx, store, patterns, exclusions, job.ContinueOnError)
+	if err != nil {
+		return fmt.Errorf("unable to match URLs: %w", err)
+	}
+	for _, raveURL := range matchingURLs {
+		raveURL.Domain = options.Domain
+		if err := processRaveURL(ctx, store, raveURL, options); err != nil {
+			if ctx.Err() != nil {
+				log.Println("Cancelled processing", raveURL.URL())
+				return err
+			}
+			if !job.ContinueOnError {
+				return fmt.Errorf("unable to process %s: %w", raveURL.URL(), err)
+			}
+			log.Println("Skipping URL", raveURL.URL(), ":", err)
+		}
 	}
+	return nil
 }
 
 func main() {
-	var patternsArray, raveUrls arrayFlags
-	flag.Var(&patternsArray, "pattern", "Supply the URL patterns")
-	flag.Var(&raveUrls, "url", "Specific Rave URLs")
+	// a subcommand comes before the flags
+	args := os.Args[1:]
+	var command string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	job := defaultReportJob()
+	flag.Var((*arrayFlags)(&job.Patterns), "pattern", "Supply the URL patterns")
+	flag.Var((*arrayFlags)(&job.URLs), "url", "Specific Rave URLs")
+	flag.Var((*arrayFlags)(&job.Exclude), "exclude", "URL patterns to exclude from the matches")
+	flag.StringVar(&job.Domain, "domain", job.Domain, "Rave domain appended to -url and removed for the URL prefix, may be empty")
+	flag.StringVar(&job.Match, "match", job.Match, "How -pattern and -exclude are matched: substring, exact, glob or regex")
 	dumpURLs := flag.Bool("listurls", false, "Dump the list of urls")
-	hostName := flag.String("dbhost", "localhost", "Database Host")
-	dbName := flag.String("dbname", "editsfive", "Database Name")
-	dbUser := flag.String("user", "edits", "Database User")
-	dbPass := flag.String("password", "apple01", "Database Password")
-	//fileName := flag.String("output", "report", "Output File Name")
-	//threshold := flag.Int("threshold", 10, "Threshold for Reporting")
-	flag.Parse()
-	if *dumpURLs == false && (len(patternsArray) == 0 && len(raveUrls) == 0) {
-		log.Fatal("Need to specify the patterns or url")
-	}
-	var dataSourceName = fmt.Sprintf("host=%s user=%s dbname=%s password=%s sslmode=disable",
-		*hostName,
-		*dbUser,
-		*dbName,
-		*dbPass)
-	var dbConn *sqlx.DB
-	// make the database connection
-	dbConn, err := sqlx.Open("postgres", string(dataSourceName))
-	if err != nil {
-		log.Fatal(err)
+	var history HistorySettings
+	flag.StringVar(&history.Schema, "history-schema", "", "Save a snapshot of each run to the tables in this Postgres schema")
+	flag.StringVar(&history.DSN, "history-dsn", "", "Connection for the snapshots (default the report database)")
+	configFile := flag.String("config", "", "Run the report jobs in a JSON configuration file")
+	var connection ConnectionSettings
+	flag.StringVar(&connection.DSN, "dsn", "", "Database connection string or URL (default $DATABASE_URL, unless the other connection flags are set)")
+	flag.StringVar(&connection.Host, "dbhost", "", "Database Host (default $PGHOST or localhost)")
+	flag.StringVar(&connection.Port, "dbport", "", "Database Port (default $PGPORT or 5432)")
+	flag.StringVar(&connection.DBName, "dbname", "", "Database Name (default $PGDATABASE or editsfive)")
+	flag.StringVar(&connection.User, "user", "", "Database User (default $PGUSER or edits)")
+	flag.StringVar(&connection.SSLMode, "sslmode", "", "Database SSL mode (default $PGSSLMODE or require)")
+	flag.StringVar(&connection.SSLRootCert, "sslrootcert", "", "Database SSL root certificate (default $PGSSLROOTCERT)")
+	fixture := flag.String("fixture", "", "Load the data from a fixture file rather than the database")
+	flag.BoolVar(&job.ContinueOnError, "continue", false, "Skip the projects and URLs that fail to load")
+	workers := flag.Int("workers", 4, "Number of projects to load concurrently")
+	queryTimeout := flag.Duration("query-timeout", 0, "Limit on the time for a single query (eg 5m), 0 for no limit")
+	rulesFile := flag.String("rules", "", "JSON file of the edit check classification rules (default the SYS_ prefixes)")
+	flag.StringVar(&job.OutputDir, "output-dir", "", "Directory for the workbooks (default the current directory)")
+	flag.StringVar(&job.FilenameTemplate, "output", job.FilenameTemplate,
+		"Workbook file name, with the placeholders "+strings.Join(filenamePlaceholders, ", "))
+	flag.StringVar(&job.Format, "format", job.Format, "Output format: xlsx, csv (a directory of CSV files per URL), json, html, parquet (a directory per URL), or table / markdown to print a summary")
+	flag.StringVar(&job.EditStatus, "edit-status", job.EditStatus, "The edit check metrics to report: active, inactive or all for both")
+	flag.StringVar(&job.OnCollision, "on-collision", job.OnCollision, "When the workbook exists: suffix, refuse or overwrite")
+	flag.Var((*arrayFlags)(&job.Sheets), "sheet", "A sheet to write, may be repeated (default all but "+strings.Join(optionalSheets, ", ")+")")
+	flag.IntVar(&job.RankingSize, "ranking-size", job.RankingSize, "Number of checks in each top and bottom N of the Check Ranking sheet")
+	var cohorts arrayFlags
+	flag.Var(&cohorts, "cohort", `Summary cohort, eg "Large:subject_count > 100,completed_count >= 1" (default the standard cohorts)`)
+	_ = flag.CommandLine.Parse(args)
+	if *workers < 1 {
+		log.Fatal("-workers must be at least 1")
 	}
-	if *dumpURLs == true {
-		getURLs(dbConn)
-		os.Exit(0)
+	for _, cohort := range cohorts {
+		job.Cohorts = append(job.Cohorts, parseCohort(cohort))
 	}
-	if len(raveUrls) != 0 {
-		for _, raveURL := range raveUrls {
-			if !strings.HasSuffix(raveURL, ".mdsol.com") {
-				// if we don't end with mdsol.com, then set it
-				patternsArray.Set(fmt.Sprintf("%s.mdsol.com", raveURL))
-			} else {
-				patternsArray.Set(raveURL)
+	jobs := []ReportJob{job}
+	if *configFile != "" {
+		// the jobs come from the configuration file, so the job flags would be ignored
+		var jobFlags []string
+		flag.Visit(func(f *flag.Flag) {
+			if isJobFlag(f.Name) {
+				jobFlags = append(jobFlags, "-"+f.Name)
 			}
-
+		})
+		if len(jobFlags) != 0 {
+			log.Fatal("Can't combine ", strings.Join(jobFlags, ", "), " with -config, set them in the configuration file's jobs")
+		}
+		config, err := loadReportConfig(*configFile)
+		if err != nil {
+			log.Fatal("Unable to load configuration: ", err)
+		}
+		// the flags fill in anything the configuration leaves out
+		connection = config.Connection.withFallback(connection)
+		if config.Fixture != "" {
+			*fixture = config.Fixture
+		}
+		if config.Workers != nil {
+			*workers = *config.Workers
+		}
+		if *queryTimeout, err = config.queryTimeout(*queryTimeout); err != nil {
+			log.Fatal(err)
+		}
+		if config.Rules != "" {
+			*rulesFile = config.Rules
+		}
+		if config.History.Schema != "" {
+			history = config.History
 		}
+		jobs = config.Jobs
 	}
-	for _, urlPattern := range patternsArray {
-		matchingURLs, err := GetURLsThatMatch(dbConn, urlPattern)
+	// cancel the in-flight queries on an interrupt, a second interrupt exits straight away
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Println("Received", sig, "- cancelling")
+		cancel()
+		sig = <-signals
+		log.Println("Received", sig, "again - exiting")
+		os.Exit(1)
+	}()
+	switch command {
+	case "":
+		if *dumpURLs == false && *configFile == "" && (len(job.Patterns) == 0 && len(job.URLs) == 0) {
+			log.Fatal("Need to specify the patterns, url or config")
+		}
+	case "doctor":
+		if *fixture != "" {
+			log.Fatal("doctor checks the database, not a fixture")
+		}
+	case "diff":
+		// compares saved runs, nothing is loaded
+		if err := runDiff(ctx, flag.Args(), job, history, connection); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	default:
+		log.Fatal("Unknown command ", command)
+	}
+	// check the jobs before connecting
+	for _, reportJob := range jobs {
+		if _, _, err := reportJob.matchers(); err != nil {
+			log.Fatal(err)
+		}
+		if _, err := reportJob.reportOptions(*workers); err != nil {
+			log.Fatal(err)
+		}
+	}
+	rules := defaultClassificationRules()
+	if *rulesFile != "" {
+		var err error
+		if rules, err = loadClassificationRules(*rulesFile); err != nil {
+			log.Fatal("Unable to load the classification rules: ", err)
+		}
+	}
+	var store Store
+	var dbConn *sqlx.DB
+	if *fixture != "" {
+		// load the fixture
+		memoryStore, err := newMemoryStore(*fixture, rules)
 		if err != nil {
-			continue
+			log.Fatal("Unable to load fixture: ", err)
 		}
-		if len(matchingURLs) == 0 {
-			log.Println("No matching URLs for", urlPattern)
-			continue
+		store = memoryStore
+	} else {
+		// the password comes from the environment or the password file, never the command line
+		dataSourceName, err := connection.dataSourceName()
+		if err != nil {
+			log.Fatal(err)
 		}
-
-		for _, raveURL := range matchingURLs {
-			processRaveURL(dbConn, raveURL)
+		// make the database connection
+		dbConn, err = sqlx.Open("postgres", dataSourceName)
+		if err != nil {
+			log.Fatal(err)
 		}
-
+		// one connection per worker
+		dbConn.SetMaxOpenConns(*workers)
+		dbConn.SetMaxIdleConns(*workers)
+		store = newPostgresStore(dbConn, *queryTimeout, rules)
+	}
+	var historyStore *HistoryStore
+	if history.Schema != "" && command == "" && !*dumpURLs {
+		var err error
+		historyStore, err = openHistoryStore(ctx, history, dbConn)
+		if err != nil {
+			log.Fatal("Unable to open the snapshot store: ", err)
+		}
+	}
+	if command == "doctor" {
+		if !runDoctor(ctx, newPostgresStore(dbConn, *queryTimeout, rules), os.Stdout) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *dumpURLs == true {
+		getURLs(ctx, store)
+		os.Exit(0)
+	}
+	for _, reportJob := range jobs {
+		if len(jobs) > 1 {
+			log.Println("Running job", reportJob.Name)
+		}
+		if err := runJob(ctx, store, reportJob, *workers, historyStore, rules); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if len(jobs) > 1 {
+				log.Fatal("Job ", reportJob.Name, " failed: ", err)
+			}
+			log.Fatal(err)
+		}
+	}
+	if ctx.Err() != nil {
+		log.Println("Interrupted, the completed workbooks have been saved")
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tealeg/xlsx"
+)
+
+// the fixture store and the URL in it
+func fixtureStoreURL(t *testing.T, url string) (*MemoryStore, RaveURL) {
+	t.Helper()
+	store, err := newMemoryStore(filepath.Join("fixtures", "demo.json"), defaultClassificationRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, storeURL(t, store, url)
+}
+
+// the URL in the store
+func storeURL(t *testing.T, store Store, url string) RaveURL {
+	t.Helper()
+	matcher, err := newURLMatcher(MatchExact, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls, err := store.GetURLsThatMatch(context.Background(), matcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 {
+		t.Fatalf("expected one URL for %s, got %d", url, len(urls))
+	}
+	return urls[0]
+}
+
+// the options of a job run against the fixture
+func fixtureOptions(t *testing.T, job ReportJob, workers int) ReportOptions {
+	t.Helper()
+	options, err := job.reportOptions(workers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options.Rules = defaultClassificationRules()
+	return options
+}
+
+// load a fixture URL as a report would
+func loadFixtureURL(t *testing.T, url string, job ReportJob) RaveURL {
+	t.Helper()
+	store, raveURL := fixtureStoreURL(t, url)
+	raveURL, _, err := loadRaveURL(context.Background(), store, raveURL, fixtureOptions(t, job, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raveURL
+}
+
+// run the report for a fixture URL into the directory, returning the URL reported on
+func runFixtureReport(t *testing.T, url string, job ReportJob, workers int, dir string) RaveURL {
+	t.Helper()
+	store, _ := fixtureStoreURL(t, url)
+	return runStoreReport(t, store, url, job, workers, dir)
+}
+
+// run the report for a URL in the store into the directory
+func runStoreReport(t *testing.T, store Store, url string, job ReportJob, workers int, dir string) RaveURL {
+	t.Helper()
+	raveURL := storeURL(t, store, url)
+	job.OutputDir = dir
+	job.FilenameTemplate = "{prefix}.xlsx"
+	options := fixtureOptions(t, job, workers)
+	raveURL.Domain = options.Domain
+	if err := processRaveURL(context.Background(), store, raveURL, options); err != nil {
+		t.Fatal(err)
+	}
+	return raveURL
+}
+
+// run the report for a fixture URL into a temporary directory and open the workbook
+func fixtureWorkbook(t *testing.T, url string, job ReportJob) *xlsx.File {
+	t.Helper()
+	store, _ := fixtureStoreURL(t, url)
+	return storeWorkbook(t, store, url, job, 1)
+}
+
+// run the report for a URL in the store into a temporary directory and open the workbook
+func storeWorkbook(t *testing.T, store Store, url string, job ReportJob, workers int) *xlsx.File {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "projector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	raveURL := runStoreReport(t, store, url, job, workers, dir)
+	workbook, err := xlsx.OpenFile(filepath.Join(dir, raveURL.URLPrefix()+".xlsx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return workbook
+}
+
+// the cells of the sheet as strings, failing when the sheet is missing
+func sheetRows(t *testing.T, workbook *xlsx.File, name string) [][]string {
+	t.Helper()
+	sheet, ok := workbook.Sheet[name]
+	if !ok {
+		t.Fatalf("no %q sheet", name)
+	}
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		var cells []string
+		for _, cell := range row.Cells {
+			cells = append(cells, cell.String())
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+func TestProcessRaveURL(t *testing.T) {
+	workbook := fixtureWorkbook(t, "pharma.mdsol.com", defaultReportJob())
+	for _, name := range []string{
+		"Subject Counts",
+		"Unused Edits w OpenQuery",
+		"Unused Edits wo OpenQuery",
+		"pharma",
+		"pharma - Last",
+		"Summary Counts",
+	} {
+		if _, ok := workbook.Sheet[name]; !ok {
+			t.Errorf("no %q sheet", name)
+		}
+	}
+	// the optional sheets are only written when asked for
+	for _, name := range []string{"Version Diff", "Check Ranking", "Edit Check Detail"} {
+		if _, ok := workbook.Sheet[name]; ok {
+			t.Errorf("unexpected %q sheet", name)
+		}
+	}
+
+	subjects := sheetRows(t, workbook, "Subject Counts")
+	if len(subjects) != 3 {
+		t.Fatalf("expected a header and 2 projects in Subject Counts, got %d rows", len(subjects))
+	}
+	for idx, expected := range [][]string{
+		{"pharma.mdsol.com", "Alpha", "42"},
+		{"pharma.mdsol.com", "Beta", "6"},
+	} {
+		if got := subjects[idx+1][:3]; !equalStrings(got, expected) {
+			t.Errorf("Subject Counts row %d: expected %v, got %v", idx+1, expected, got)
+		}
+	}
+
+	var unused []string
+	for _, row := range sheetRows(t, workbook, "Unused Edits w OpenQuery")[1:] {
+		unused = append(unused, row[0]+"/"+row[1])
+	}
+	expectedUnused := []string{
+		"Alpha/CM_DUPLICATE_MED",
+		"Alpha/SYS_FUTURE_VS_VSDAT",
+		"Alpha/SYS_NC_AE_AESTDAT",
+		"Beta/EX_DOSE_CHECK",
+		"Beta/SYS_FUTURE_DM_DAT",
+	}
+	if !equalStrings(unused, expectedUnused) {
+		t.Errorf("unused edits with OpenQuery: expected %v, got %v", expectedUnused, unused)
+	}
+
+	last := sheetRows(t, workbook, "pharma - Last")
+	if len(last) != 3 {
+		t.Fatalf("expected a header and 2 projects in the last versions, got %d rows", len(last))
+	}
+	// the project, version, subjects, active and inactive edits
+	for idx, expected := range [][]string{
+		{"Alpha", "101", "42", "8", "0"},
+		{"Beta", "200", "6", "3", "1"},
+	} {
+		if got := last[idx+1][:5]; !equalStrings(got, expected) {
+			t.Errorf("last version row %d: expected %v, got %v", idx+1, expected, got)
+		}
+	}
+}
+
+func TestProcessRaveURLSheets(t *testing.T) {
+	job := defaultReportJob()
+	job.Sheets = []string{SheetSubjectCounts}
+	workbook := fixtureWorkbook(t, "pharma.mdsol.com", job)
+	if len(workbook.Sheets) != 1 || workbook.Sheets[0].Name != "Subject Counts" {
+		var names []string
+		for _, sheet := range workbook.Sheets {
+			names = append(names, sheet.Name)
+		}
+		t.Errorf("expected only the Subject Counts sheet, got %v", names)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestProcessRaveURLInactiveEdits(t *testing.T) {
+	job := defaultReportJob()
+	job.EditStatus = AllChecks.String()
+	workbook := fixtureWorkbook(t, "pharma.mdsol.com", job)
+	for _, name := range []string{
+		"pharma",
+		"pharma (inactive)",
+		"pharma - Last",
+		"pharma - Last (inactive)",
+		"Summary Counts",
+		"Summary Counts (inactive)",
+	} {
+		if _, ok := workbook.Sheet[name]; !ok {
+			t.Errorf("no %q sheet", name)
+		}
+	}
+	// the project, version, subjects, active and inactive edits, then the first set of
+	// metrics, which has Beta's inactive check and nothing recorded for Alpha
+	last := sheetRows(t, workbook, "pharma - Last (inactive)")
+	for idx, expected := range [][]string{
+		{"Alpha", "101", "42", "8", "0", "-1"},
+		{"Beta", "200", "6", "3", "1", "1"},
+	} {
+		if got := last[idx+1][:6]; !equalStrings(got, expected) {
+			t.Errorf("inactive last version row %d: expected %v, got %v", idx+1, expected, got)
+		}
+	}
+
+	job.EditStatus = InactiveChecks.String()
+	workbook = fixtureWorkbook(t, "pharma.mdsol.com", job)
+	if _, ok := workbook.Sheet["pharma - Last"]; ok {
+		t.Error("expected only the inactive metrics")
+	}
+	// the summary leaves out the metrics that weren't recorded
+	summary := sheetRows(t, workbook, "Summary Counts (inactive)")
+	if got := summary[1][:6]; !equalStrings(got, []string{"All Projects", "Sum", "ALL", "2", "48", "1"}) {
+		t.Errorf("unexpected inactive summary %v", got)
+	}
+}
+
+func TestProcessRaveURLSummaryWithoutProgrammedEdits(t *testing.T) {
+	refreshDate := time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC)
+	store := &MemoryStore{
+		URLs: []fixtureURL{{URLID: 1, URL: "cro.mdsol.com"}},
+		Projects: []fixtureProject{
+			{ProjectID: 10, URLID: 1, ProjectName: "Alpha"},
+			{ProjectID: 11, URLID: 1, ProjectName: "Delta"},
+		},
+		LastVersions: []fixtureLastVersion{{ProjectID: 10, CRFVersionID: 100}, {ProjectID: 11, CRFVersionID: 110}},
+		RefreshDates: []fixtureRefreshDate{{ProjectID: 10, RefreshDate: refreshDate}, {ProjectID: 11, RefreshDate: refreshDate}},
+		EditChecks: []fixtureEditCheck{
+			{URLID: 1, ProjectID: 10, CRFVersionID: 100, EditCheckName: "SYS_REQ_DM_SEX", Actions: "OpenQuery",
+				IsActive: 1, TotalCheckExecutions: 4, ChangeCount: 3, NoChangeCount: 1, SubjectCount: 10},
+			{URLID: 1, ProjectID: 10, CRFVersionID: 100, EditCheckName: "DM_AGE", Actions: "OpenQuery",
+				IsActive: 1, TotalCheckExecutions: 2, ChangeCount: 2, SubjectCount: 10},
+			// the last version of Delta has only field edits
+			{URLID: 1, ProjectID: 11, CRFVersionID: 110, EditCheckName: "SYS_REQ_AE_TERM", Actions: "OpenQuery",
+				IsActive: 1, TotalCheckExecutions: 5, ChangeCount: 1, NoChangeCount: 4, SubjectCount: 4},
+		},
+		rules: defaultClassificationRules(),
+	}
+	job := defaultReportJob()
+	job.Sheets = []string{SheetLastVersion, SheetSummaryCounts}
+	workbook := storeWorkbook(t, store, "cro.mdsol.com", job, 1)
+	// the programmed edits Delta doesn't have add nothing to the sums, rather than
+	// taking one off; the criteria, aggregate, threshold, sample and subject counts,
+	// then the total, field and programmed checks
+	summary := sheetRows(t, workbook, "Summary Counts")
+	for idx, expected := range [][]string{
+		{"All Projects", "Sum", "ALL", "2", "14", "3", "2"},
+		{"All Projects", "Average", "ALL", "2", "7.00", "1.50", "1.00"},
+	} {
+		if got := summary[idx+1][:7]; !equalStrings(got, expected) {
+			t.Errorf("summary row %d: expected %v, got %v", idx+1, expected, got)
+		}
+	}
+	if got := summary[1][16]; got != "1" {
+		t.Errorf("expected the one programmed check of Alpha, got %s", got)
+	}
+	if got := summary[2][16]; got != "0.50" {
+		t.Errorf("expected half a programmed check on average, got %s", got)
+	}
+}
+
+func TestProcessRaveURLWorkers(t *testing.T) {
+	job := defaultReportJob()
+	job.EditStatus = AllChecks.String()
+	job.Sheets = append(append([]string{}, allSheets...), optionalSheets...)
+	store, _ := fixtureStoreURL(t, "pharma.mdsol.com")
+	serial := storeWorkbook(t, store, "pharma.mdsol.com", job, 1)
+	concurrent := storeWorkbook(t, store, "pharma.mdsol.com", job, 4)
+	if len(serial.Sheets) != len(concurrent.Sheets) {
+		t.Fatalf("expected %d sheets with 4 workers, got %d", len(serial.Sheets), len(concurrent.Sheets))
+	}
+	// the workers only change how the projects load, not the workbook
+	for idx, sheet := range serial.Sheets {
+		if concurrent.Sheets[idx].Name != sheet.Name {
+			t.Errorf("sheet %d: expected %q, got %q", idx, sheet.Name, concurrent.Sheets[idx].Name)
+			continue
+		}
+		expected := sheetRows(t, serial, sheet.Name)
+		got := sheetRows(t, concurrent, sheet.Name)
+		if len(got) != len(expected) {
+			t.Errorf("%s: expected %d rows, got %d", sheet.Name, len(expected), len(got))
+			continue
+		}
+		for row := range expected {
+			if !equalStrings(got[row], expected[row]) {
+				t.Errorf("%s row %d: expected %v, got %v", sheet.Name, row, expected[row], got[row])
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// What to do when the report file already exists
+type CollisionPolicy int
+
+const (
+	CollisionSuffix CollisionPolicy = iota
+	CollisionRefuse
+	CollisionOverwrite
+)
+
+var collisionPolicyNames = []string{"suffix", "refuse", "overwrite"}
+
+func (policy CollisionPolicy) String() string {
+	return collisionPolicyNames[policy]
+}
+
+// parse the name of a collision policy
+func parseCollisionPolicy(name string) (CollisionPolicy, error) {
+	for idx, policyName := range collisionPolicyNames {
+		if strings.EqualFold(name, policyName) {
+			return CollisionPolicy(idx), nil
+		}
+	}
+	return CollisionSuffix, fmt.Errorf("unknown collision policy %q, expected one of %s",
+		name, strings.Join(collisionPolicyNames, ", "))
+}
+
+// The format the report is written in
+type ReportFormat int
+
+const (
+	FormatXLSX ReportFormat = iota
+	FormatCSV
+	FormatJSON
+	FormatHTML
+	FormatTable
+	FormatMarkdown
+	FormatParquet
+)
+
+var reportFormatNames = []string{"xlsx", "csv", "json", "html", "table", "markdown", "parquet"}
+
+func (format ReportFormat) String() string {
+	return reportFormatNames[format]
+}
+
+// parse the name of a report format
+func parseReportFormat(name string) (ReportFormat, error) {
+	for idx, formatName := range reportFormatNames {
+		if strings.EqualFold(name, formatName) {
+			return ReportFormat(idx), nil
+		}
+	}
+	return FormatXLSX, fmt.Errorf("unknown format %q, expected one of %s",
+		name, strings.Join(reportFormatNames, ", "))
+}
+
+// the placeholders available in a filename template
+var filenamePlaceholders = []string{"{prefix}", "{url}", "{date}", "{time}", "{pattern}", "{job}"}
+
+// make a value safe to use within a file name
+func sanitizeFileName(value string) string {
+	return strings.Map(func(char rune) rune {
+		switch char {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', '^', '$':
+			return '_'
+		}
+		if char < ' ' {
+			return '_'
+		}
+		return char
+	}, value)
+}
+
+// check the template only uses the known placeholders
+func checkFilenameTemplate(template string) error {
+	if template == "" {
+		return fmt.Errorf("empty filename template")
+	}
+	remaining := template
+	for _, placeholder := range filenamePlaceholders {
+		remaining = strings.Replace(remaining, placeholder, "", -1)
+	}
+	if start := strings.Index(remaining, "{"); start >= 0 && strings.Contains(remaining[start:], "}") {
+		return fmt.Errorf("unknown placeholder in filename template %q, expected %s",
+			template, strings.Join(filenamePlaceholders, ", "))
+	}
+	return nil
+}
+
+// the file name for the report on a RaveURL
+func (options ReportOptions) reportFileName(raveURL RaveURL, now time.Time) string {
+	fileName := strings.NewReplacer(
+		"{prefix}", sanitizeFileName(raveURL.URLPrefix()),
+		"{url}", sanitizeFileName(raveURL.URL()),
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("150405"),
+		"{pattern}", sanitizeFileName(raveURL.MatchedPattern),
+		"{job}", sanitizeFileName(options.JobName),
+	).Replace(options.FilenameTemplate)
+	return filepath.Join(options.OutputDir, fileName)
+}
+
+// swap the extension of the file name
+func replaceExtension(fileName, extension string) string {
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName)) + extension
+}
+
+// the name with a numbered suffix before the extension, eg report_2.xlsx
+func suffixedFileName(fileName string, number int) string {
+	extension := filepath.Ext(fileName)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(fileName, extension), number, extension)
+}
+
+// write the file atomically, via a temporary file in the same directory, returning
+// the name it was saved as
+func writeFileAtomic(fileName string, policy CollisionPolicy, write func(io.Writer) error) (saved string, err error) {
+	dir := filepath.Dir(fileName)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	tempFile, err := ioutil.TempFile(dir, "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	// never leave the temporary file behind
+	defer os.Remove(tempFile.Name())
+	if err = write(tempFile); err != nil {
+		tempFile.Close()
+		return "", err
+	}
+	if err = tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return "", err
+	}
+	if err = tempFile.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Chmod(tempFile.Name(), 0644); err != nil {
+		return "", err
+	}
+	if policy == CollisionOverwrite {
+		return fileName, os.Rename(tempFile.Name(), fileName)
+	}
+	// a link fails if the target exists, so an existing report is never replaced
+	for number := 1; ; number++ {
+		saved = fileName
+		if number > 1 {
+			saved = suffixedFileName(fileName, number)
+		}
+		err = linkNewFile(tempFile.Name(), saved)
+		if err == nil {
+			return saved, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		if policy == CollisionRefuse {
+			return "", fmt.Errorf("%s already exists", fileName)
+		}
+	}
+}
+
+// link the temporary file to the new name, failing if the name exists; a filesystem
+// without hard links (eg FAT or some network shares) claims the name with an exclusive
+// create instead and renames over it
+func linkNewFile(tempName, fileName string) error {
+	err := os.Link(tempName, fileName)
+	if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENOTSUP) {
+		return claimNewFile(tempName, fileName)
+	}
+	return err
+}
+
+// create the file exclusively, so nothing else can take the name, then replace it with
+// the temporary file
+func claimNewFile(tempName, fileName string) error {
+	placeholder, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if err = placeholder.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tempName, fileName); err != nil {
+		os.Remove(fileName)
+		return err
+	}
+	return nil
+}
+
+// write a directory by filling a temporary directory alongside it and renaming it
+// into place, returning the name it was saved as.  A new directory appears complete
+// or not at all; an overwritten one is moved aside before the new one is renamed in,
+// so the name is briefly missing but never refers to a partly written directory
+func writeDirAtomic(dirName string, policy CollisionPolicy, write func(dir string) error) (saved string, err error) {
+	parent := filepath.Dir(dirName)
+	if err = os.MkdirAll(parent, 0755); err != nil {
+		return "", err
+	}
+	tempDir, err := ioutil.TempDir(parent, "."+filepath.Base(dirName)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	// never leave the temporary directory behind
+	defer os.RemoveAll(tempDir)
+	if err = write(tempDir); err != nil {
+		return "", err
+	}
+	if err = os.Chmod(tempDir, 0755); err != nil {
+		return "", err
+	}
+	for number := 1; ; number++ {
+		saved = dirName
+		if number > 1 {
+			saved = fmt.Sprintf("%s_%d", dirName, number)
+		}
+		_, err = os.Lstat(saved)
+		if os.IsNotExist(err) {
+			return saved, os.Rename(tempDir, saved)
+		}
+		if err != nil {
+			return "", err
+		}
+		switch policy {
+		case CollisionRefuse:
+			return "", fmt.Errorf("%s already exists", dirName)
+		case CollisionOverwrite:
+			oldDir := tempDir + ".old"
+			if err = os.Rename(saved, oldDir); err != nil {
+				return "", err
+			}
+			if err = os.Rename(tempDir, saved); err != nil {
+				// put the old directory back
+				if restoreErr := os.Rename(oldDir, saved); restoreErr != nil {
+					return "", fmt.Errorf("%w, and restoring %s from %s failed: %v", err, saved, oldDir, restoreErr)
+				}
+				return "", err
+			}
+			return saved, os.RemoveAll(oldDir)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// write the content with the policy, returning the name it was saved as
+func writeTestFile(fileName string, policy CollisionPolicy, content string) (string, error) {
+	return writeFileAtomic(fileName, policy, func(out io.Writer) error {
+		_, err := io.WriteString(out, content)
+		return err
+	})
+}
+
+// fail unless the file holds the content
+func checkFileContent(t *testing.T, fileName, expected string) {
+	t.Helper()
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != expected {
+		t.Errorf("%s: expected %q, got %q", fileName, expected, content)
+	}
+}
+
+// fail if any temporary files were left in the directory
+func checkNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") || strings.HasSuffix(entry.Name(), ".old") {
+			t.Errorf("left %s behind", entry.Name())
+		}
+	}
+}
+
+func tempTestDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "projector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSuffixedFileName(t *testing.T) {
+	tests := []struct {
+		fileName string
+		number   int
+		expected string
+	}{
+		{"report.xlsx", 2, "report_2.xlsx"},
+		{"out/pharma_2020-01-31.json", 3, "out/pharma_2020-01-31_3.json"},
+		{"report", 2, "report_2"},
+	}
+	for _, test := range tests {
+		if got := suffixedFileName(test.fileName, test.number); got != test.expected {
+			t.Errorf("suffixedFileName(%q, %d): expected %q, got %q", test.fileName, test.number, test.expected, got)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		policy   CollisionPolicy
+		saved    string
+		contents map[string]string
+		fails    bool
+	}{
+		{CollisionSuffix, "report_3.xlsx", map[string]string{
+			"report.xlsx": "first", "report_2.xlsx": "second", "report_3.xlsx": "new"}, false},
+		{CollisionRefuse, "", map[string]string{
+			"report.xlsx": "first", "report_2.xlsx": "second"}, true},
+		{CollisionOverwrite, "report.xlsx", map[string]string{
+			"report.xlsx": "new", "report_2.xlsx": "second"}, false},
+	}
+	for _, test := range tests {
+		dir := tempTestDir(t)
+		defer os.RemoveAll(dir)
+		fileName := filepath.Join(dir, "report.xlsx")
+		for name, content := range map[string]string{"report.xlsx": "first", "report_2.xlsx": "second"} {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		saved, err := writeTestFile(fileName, test.policy, "new")
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.policy)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", test.policy, err)
+		} else if saved != filepath.Join(dir, test.saved) {
+			t.Errorf("%s: expected %s, got %s", test.policy, test.saved, saved)
+		}
+		for name, content := range test.contents {
+			checkFileContent(t, filepath.Join(dir, name), content)
+		}
+		checkNoTempFiles(t, dir)
+	}
+}
+
+func TestWriteFileAtomicNewDirectory(t *testing.T) {
+	dir := tempTestDir(t)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "reports", "summary", "report.json")
+	saved, err := writeTestFile(fileName, CollisionRefuse, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != fileName {
+		t.Errorf("expected %s, got %s", fileName, saved)
+	}
+	checkFileContent(t, fileName, "new")
+}
+
+func TestClaimNewFile(t *testing.T) {
+	dir := tempTestDir(t)
+	defer os.RemoveAll(dir)
+	tempName := filepath.Join(dir, "temp")
+	if err := ioutil.WriteFile(tempName, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(dir, "existing.xlsx")
+	if err := ioutil.WriteFile(existing, []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the fallback for filesystems without hard links never replaces a file
+	if err := claimNewFile(tempName, existing); !os.IsExist(err) {
+		t.Errorf("expected an exists error, got %v", err)
+	}
+	checkFileContent(t, existing, "first")
+	fileName := filepath.Join(dir, "report.xlsx")
+	if err := claimNewFile(tempName, fileName); err != nil {
+		t.Fatal(err)
+	}
+	checkFileContent(t, fileName, "new")
+	if _, err := os.Stat(tempName); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file to be renamed, got %v", err)
+	}
+}
+
+func TestWriteDirAtomic(t *testing.T) {
+	tests := []struct {
+		policy   CollisionPolicy
+		saved    string
+		contents map[string]string
+		fails    bool
+	}{
+		{CollisionSuffix, "report_2", map[string]string{
+			"report/old.csv": "first", "report_2/new.csv": "new"}, false},
+		{CollisionRefuse, "", map[string]string{"report/old.csv": "first"}, true},
+		{CollisionOverwrite, "report", map[string]string{"report/new.csv": "new"}, false},
+	}
+	for _, test := range tests {
+		dir := tempTestDir(t)
+		defer os.RemoveAll(dir)
+		dirName := filepath.Join(dir, "report")
+		if err := os.Mkdir(dirName, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dirName, "old.csv"), []byte("first"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		saved, err := writeDirAtomic(dirName, test.policy, func(tempDir string) error {
+			return ioutil.WriteFile(filepath.Join(tempDir, "new.csv"), []byte("new"), 0644)
+		})
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.policy)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", test.policy, err)
+		} else if saved != filepath.Join(dir, test.saved) {
+			t.Errorf("%s: expected %s, got %s", test.policy, test.saved, saved)
+		}
+		for name, content := range test.contents {
+			checkFileContent(t, filepath.Join(dir, name), content)
+		}
+		if test.policy == CollisionOverwrite {
+			if _, err := os.Stat(filepath.Join(dirName, "old.csv")); !os.IsNotExist(err) {
+				t.Errorf("expected the old directory to be replaced, got %v", err)
+			}
+		}
+		checkNoTempFiles(t, dir)
+	}
+}
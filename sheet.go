@@ -1,13 +1,54 @@
 package main
 
 import (
-	"log"
+	"fmt"
 
 	"github.com/tealeg/xlsx"
 )
 
 const MaxWidth float64 = 70.0
 
+// the longest sheet name Excel allows, in characters
+const MaxSheetNameLength = 31
+
+// the names used to enable the sheets in a report job
+const (
+	SheetSubjectCounts = "subject_counts"
+	SheetUnusedEdits   = "unused_edits"
+	SheetVersions      = "versions"
+	SheetLastVersion   = "last_version"
+	SheetSummaryCounts = "summary_counts"
+	// optional sheets, only written when asked for
+	SheetVersionDiff     = "version_diff"
+	SheetCheckRanking    = "check_ranking"
+	SheetEditCheckDetail = "edit_check_detail"
+)
+
+var allSheets = []string{
+	SheetSubjectCounts,
+	SheetUnusedEdits,
+	SheetVersions,
+	SheetLastVersion,
+	SheetSummaryCounts,
+}
+
+// the sheets that aren't written by default
+var optionalSheets = []string{
+	SheetVersionDiff,
+	SheetCheckRanking,
+	SheetEditCheckDetail,
+}
+
+// is the name one of the sheets
+func isKnownSheet(name string) bool {
+	for _, sheet := range append(allSheets, optionalSheets...) {
+		if sheet == name {
+			return true
+		}
+	}
+	return false
+}
+
 // initialise the set of columns
 func initColumns(headers []string) []float64 {
 	var colMax []float64
@@ -17,18 +58,37 @@ func initColumns(headers []string) []float64 {
 	return colMax
 }
 
-// wrap the adding of a sheet
-func getOrAddSheet(wbk *xlsx.File, name string) (*xlsx.Sheet, bool) {
+// a sheet name of the prefix and suffix, cutting the prefix short so the suffix
+// survives when the name is too long for Excel
+func sheetName(prefix, suffix string) string {
+	name := []rune(prefix)
+	room := MaxSheetNameLength - len([]rune(suffix))
+	if room < 0 {
+		room = 0
+	}
+	if len(name) > room {
+		name = name[:room]
+	}
+	full := []rune(string(name) + suffix)
+	if len(full) > MaxSheetNameLength {
+		full = full[:MaxSheetNameLength]
+	}
+	return string(full)
+}
+
+// wrap the adding of a sheet, the name is shortened to fit
+func getOrAddSheet(wbk *xlsx.File, name string) (*xlsx.Sheet, bool, error) {
+	name = sheetName(name, "")
 	for _, sheet := range wbk.Sheets {
 		if sheet.Name == name {
-			return sheet, false
+			return sheet, false, nil
 		}
 	}
 	sheet, err := wbk.AddSheet(name)
 	if err != nil {
-		log.Fatal("Unable to create sheet", name, ": ", err)
+		return nil, false, fmt.Errorf("unable to create sheet %s: %w", name, err)
 	}
-	return sheet, true
+	return sheet, true, nil
 }
 
 // Write the Header row
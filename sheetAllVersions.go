@@ -45,7 +45,13 @@ func writeEditMetricType(editCheckTypeMetric *EditTypeMetric, row *xlsx.Row) {
 	cell.SetInt(editCheckTypeMetric.TotalOpenQueries)
 }
 
-func writeStudyMetricsForProject(urlName string, project *Project, wbk *xlsx.File) {
+// the name of the sheet of versions, labelled for the inactive edits
+func versionSheetName(urlName string, status EditStatusFilter) string {
+	return sheetName(urlName, status.sheetSuffix())
+}
+
+func writeStudyMetricsForProject(urlName string, project *Project, status EditStatusFilter, wbk *xlsx.File) error {
+	tabName := versionSheetName(urlName, status)
 	// standard headers
 	headers := []string{"Project Name",
 		"CRF Version",
@@ -79,9 +85,13 @@ func writeStudyMetricsForProject(urlName string, project *Project, wbk *xlsx.Fil
 	//}
 	var sheet *xlsx.Sheet
 	var created bool
+	var err error
 	for _, projectVersion := range project.Versions {
 		// create the sheet
-		sheet, created = getOrAddSheet(wbk, urlName)
+		sheet, created, err = getOrAddSheet(wbk, tabName)
+		if err != nil {
+			return err
+		}
 		// setup the fields
 		if created {
 			// Add the headers
@@ -113,17 +123,24 @@ func writeStudyMetricsForProject(urlName string, project *Project, wbk *xlsx.Fil
 		// InActiveEdits
 		cell = row.AddCell()
 		cell.SetInt(projectVersion.EditStatus.InactiveEdits)
+		fieldMetrics, programMetrics := projectVersion.editMetrics(status)
 		// write the program metrics
-		writeEditMetricType(&projectVersion.ProgramEditMetrics, row)
+		writeEditMetricType(programMetrics, row)
 		// write the field metrics
-		writeEditMetricType(&projectVersion.FieldEditMetrics, row)
+		writeEditMetricType(fieldMetrics, row)
 	}
 	autoSizeSheet(sheet)
+	return nil
+}
+
+// the name of the sheet of last versions, the URL prefix is cut short to fit
+func lastVersionSheetName(urlName string, status EditStatusFilter) string {
+	return sheetName(urlName, " - Last"+status.sheetSuffix())
 }
 
 // Just for the last version
-func writeLastStudyMetricsForProject(urlName string, project *Project, wbk *xlsx.File) {
-	tabName := urlName + " - Last"
+func writeLastStudyMetricsForProject(urlName string, project *Project, status EditStatusFilter, wbk *xlsx.File) error {
+	tabName := lastVersionSheetName(urlName, status)
 	// standard headers
 	headers := []string{"Project Name",
 		"CRF Version",
@@ -158,12 +175,16 @@ func writeLastStudyMetricsForProject(urlName string, project *Project, wbk *xlsx
 	//
 	var sheet *xlsx.Sheet
 	var created bool
+	var err error
 	for _, projectVersion := range project.Versions {
 		if !projectVersion.LastVersion {
 			continue
 		}
 		// create the sheet
-		sheet, created = getOrAddSheet(wbk, tabName)
+		sheet, created, err = getOrAddSheet(wbk, tabName)
+		if err != nil {
+			return err
+		}
 		// setup the fields
 		if created {
 			// Add the headers
@@ -203,10 +224,12 @@ func writeLastStudyMetricsForProject(urlName string, project *Project, wbk *xlsx
 		// InActiveEdits
 		cell = row.AddCell()
 		cell.SetInt(projectVersion.EditStatus.InactiveEdits)
+		fieldMetrics, programMetrics := projectVersion.editMetrics(status)
 		// write the program metrics
-		writeEditMetricType(&projectVersion.ProgramEditMetrics, row)
+		writeEditMetricType(programMetrics, row)
 		// write the field metrics
-		writeEditMetricType(&projectVersion.FieldEditMetrics, row)
+		writeEditMetricType(fieldMetrics, row)
 	}
 	autoSizeSheet(sheet)
+	return nil
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/tealeg/xlsx"
+)
+
+// write the top and bottom edit checks for each project and across the URL
+func writeCheckRanking(projects []*Project, status EditStatusFilter, size int, wbk *xlsx.File) error {
+	tabName := "Check Ranking"
+	headers := []string{"Scope",
+		"Ranking",
+		"Position",
+		"Rank",
+		"Project Name",
+		"Edit Check Name",
+		"Form OID",
+		"Field OID",
+		"Queries Fired",
+		"Change Count",
+		"No Change Count",
+		"No Change Ratio",
+		"Change Yield",
+	}
+	// create the sheet
+	sheet, created, err := getOrAddSheet(wbk, tabName)
+	if err != nil {
+		return err
+	}
+	if created {
+		// Add the headers if it's newly created
+		writeHeaderRow(headers, sheet)
+		autoFilter := new(xlsx.AutoFilter)
+		autoFilter.TopLeftCell = "A1"
+		autoFilter.BottomRightCell = "M1"
+		sheet.AutoFilter = autoFilter
+	}
+	// a ratio, or "-" when the check never executed
+	setRatio := func(cell *xlsx.Cell, value float64, ok bool) {
+		if ok {
+			cell.SetFloatWithFormat(value, "0.00")
+		} else {
+			cell.SetString("-")
+		}
+	}
+	for _, rank := range rankProjectChecks(projects, status, size) {
+		row := sheet.AddRow()
+		row.AddCell().SetString(rank.Scope)
+		row.AddCell().SetString(rank.Measure)
+		row.AddCell().SetString(rank.Position)
+		row.AddCell().SetInt(rank.Rank)
+		row.AddCell().SetString(rank.Check.ProjectName)
+		row.AddCell().SetString(rank.Check.EditCheckName)
+		row.AddCell().SetString(rank.Check.FormOIDs)
+		row.AddCell().SetString(rank.Check.FieldOIDs)
+		row.AddCell().SetInt(rank.Check.Executions)
+		row.AddCell().SetInt(rank.Check.ChangeCount)
+		row.AddCell().SetInt(rank.Check.NoChangeCount)
+		noChangeRatio, ok := rank.Check.noChangeRatio()
+		setRatio(row.AddCell(), noChangeRatio, ok)
+		changeYield, ok := rank.Check.changeYield()
+		setRatio(row.AddCell(), changeYield, ok)
+	}
+	autoSizeSheet(sheet)
+	return nil
+}
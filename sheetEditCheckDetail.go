@@ -0,0 +1,73 @@
+package main
+
+import (
+	"github.com/tealeg/xlsx"
+)
+
+// write every edit check of the last version of the projects
+func writeEditCheckDetail(projects []*Project, wbk *xlsx.File) error {
+	tabName := "Edit Check Detail"
+	headers := []string{"Project Name",
+		"CRF Version",
+		"Edit Check Name",
+		"Form OID",
+		"Field OID",
+		"Variable OID",
+		"Actions",
+		"Active?",
+		"Total Executions",
+		"Open Checks",
+		"Change Count",
+		"No Change Count",
+		"Yield (changes per execution)",
+	}
+	// create the sheet
+	sheet, created, err := getOrAddSheet(wbk, tabName)
+	if err != nil {
+		return err
+	}
+	if created {
+		// Add the headers if it's newly created
+		writeHeaderRow(headers, sheet)
+		autoFilter := new(xlsx.AutoFilter)
+		autoFilter.TopLeftCell = "A1"
+		autoFilter.BottomRightCell = "M1"
+		sheet.AutoFilter = autoFilter
+	}
+	for _, project := range projects {
+		lastVersion := project.getLastVersion()
+		if lastVersion == nil {
+			continue
+		}
+		for _, check := range lastVersion.EditChecks {
+			var cell *xlsx.Cell
+			row := sheet.AddRow()
+			row.AddCell().SetString(project.ProjectName)
+			row.AddCell().SetInt(check.CRFVersionID)
+			row.AddCell().SetString(check.EditCheckName)
+			row.AddCell().SetString(check.FormOID)
+			row.AddCell().SetString(check.FieldOID)
+			row.AddCell().SetString(check.VariableOID)
+			row.AddCell().SetString(check.Actions)
+			cell = row.AddCell()
+			if check.IsActive == 1 {
+				cell.SetString("Y")
+			} else {
+				cell.SetString("N")
+			}
+			row.AddCell().SetInt(check.TotalCheckExecutions)
+			row.AddCell().SetInt(check.OpenChecks)
+			row.AddCell().SetInt(check.ChangeCount)
+			row.AddCell().SetInt(check.NoChangeCount)
+			// Yield
+			cell = row.AddCell()
+			if yield, ok := check.yield(); ok {
+				cell.SetFloatWithFormat(yield, "0.00")
+			} else {
+				cell.SetString("-")
+			}
+		}
+	}
+	autoSizeSheet(sheet)
+	return nil
+}
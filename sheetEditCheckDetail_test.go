@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/tealeg/xlsx"
+)
+
+func TestWriteEditCheckDetail(t *testing.T) {
+	job := defaultReportJob()
+	job.Sheets = []string{SheetEditCheckDetail}
+	workbook := fixtureWorkbook(t, "pharma.mdsol.com", job)
+	rows := sheetRows(t, workbook, "Edit Check Detail")
+	// only the last versions, 8 checks for Alpha and 4 for Beta
+	if len(rows) != 13 {
+		t.Fatalf("expected a header and 12 checks, got %d rows", len(rows))
+	}
+	byName := make(map[string][]string)
+	for _, row := range rows[1:] {
+		if row[1] != "101" && row[1] != "200" {
+			t.Errorf("expected only the last versions, got %v", row)
+		}
+		byName[row[0]+"/"+row[2]] = row
+	}
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"Alpha/SYS_REQ_DM_BRTHDAT", []string{"Alpha", "101", "SYS_REQ_DM_BRTHDAT", "DM", "BRTHDAT", "BRTHDAT",
+			"OpenQuery", "Y", "30", "1", "20", "10", "0.67"}},
+		{"Alpha/AE_ONSET_BEFORE_CONSENT", []string{"Alpha", "101", "AE_ONSET_BEFORE_CONSENT", "AE", "AESTDAT", "AESTDAT",
+			"OpenQuery|CustomFunction", "Y", "14", "2", "9", "3", "0.64"}},
+		// never executed, so no yield
+		{"Alpha/CM_DUPLICATE_MED", []string{"Alpha", "101", "CM_DUPLICATE_MED", "CM", "CMTRT", "CMTRT",
+			"OpenQuery", "Y", "0", "0", "0", "0", "-"}},
+		{"Beta/EX_DERIVE", []string{"Beta", "200", "EX_DERIVE", "EX", "EXDOSE", "EXDOSE",
+			"SetDataPoint", "N", "0", "0", "0", "0", "-"}},
+		{"Beta/SYS_REQ_DM_SEX", []string{"Beta", "200", "SYS_REQ_DM_SEX", "DM", "SEX", "SEX",
+			"OpenQuery", "Y", "3", "0", "3", "0", "1.00"}},
+	}
+	for _, test := range tests {
+		row, ok := byName[test.name]
+		if !ok {
+			t.Errorf("no row for %s", test.name)
+			continue
+		}
+		if !equalStrings(row, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, row)
+		}
+	}
+}
+
+func TestWriteEditCheckDetailWithoutLastVersion(t *testing.T) {
+	projects := []*Project{
+		{ProjectName: "Alpha", Versions: []*ProjectVersion{
+			{CRFVersionID: 100, EditChecks: []*EditCheck{{CRFVersionID: 100, EditCheckName: "DM_AGE", IsActive: 1}}},
+		}},
+		{ProjectName: "Beta"},
+	}
+	workbook := xlsx.NewFile()
+	if err := writeEditCheckDetail(projects, workbook); err != nil {
+		t.Fatal(err)
+	}
+	if rows := sheetRows(t, workbook, "Edit Check Detail"); len(rows) != 1 {
+		t.Errorf("expected only the header without a last version, got %v", rows)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/tealeg/xlsx"
+)
+
+// write the Projects that were skipped
+func writeLoadFailures(failures []LoadFailure, wbk *xlsx.File) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	tabName := "Errors"
+	headers := []string{"Rave URL",
+		"Project Name",
+		"Error",
+	}
+	// create the sheet
+	sheet, created, err := getOrAddSheet(wbk, tabName)
+	if err != nil {
+		return err
+	}
+	if created {
+		// Add the headers if it's newly created
+		writeHeaderRow(headers, sheet)
+	}
+	for _, failure := range failures {
+		var cell *xlsx.Cell
+		row := sheet.AddRow()
+		cell = row.AddCell()
+		cell.SetString(failure.URL)
+		cell = row.AddCell()
+		cell.SetString(failure.ProjectName)
+		cell = row.AddCell()
+		cell.SetString(failure.Err.Error())
+	}
+	autoSizeSheet(sheet)
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"github.com/tealeg/xlsx"
+)
+
+// the fill for the values that got worse between the runs
+func regressionStyle() *xlsx.Style {
+	style := xlsx.NewStyle()
+	style.Fill = *xlsx.NewFill("solid", "FFFFC7CE", "FFFFC7CE")
+	style.ApplyFill = true
+	return style
+}
+
+// set the cell to the value, or "-" when it wasn't recorded
+func setDeltaCell(cell *xlsx.Cell, value *int64) {
+	if value == nil {
+		cell.SetString("-")
+		return
+	}
+	cell.SetInt64(*value)
+}
+
+// write the runs that were compared
+func writeRunComparison(delta RunDelta, wbk *xlsx.File) error {
+	sheet, _, err := getOrAddSheet(wbk, "Run Comparison")
+	if err != nil {
+		return err
+	}
+	writeHeaderRow([]string{"", "Old", "New"}, sheet)
+	for _, line := range [][]string{
+		{"Rave URL", delta.URL, delta.URL},
+		{"Source", delta.Old.Source, delta.New.Source},
+		{"Job", delta.Old.Job, delta.New.Job},
+	} {
+		row := sheet.AddRow()
+		for _, value := range line {
+			row.AddCell().SetString(value)
+		}
+	}
+	row := sheet.AddRow()
+	row.AddCell().SetString("Generated")
+	row.AddCell().SetDateTime(delta.Old.GeneratedAt)
+	row.AddCell().SetDateTime(delta.New.GeneratedAt)
+	autoSizeSheet(sheet)
+	return nil
+}
+
+// write the old, new and change for each metric of the projects
+func writeProjectChanges(delta RunDelta, wbk *xlsx.File) error {
+	headers := []string{"Project Name", "Status"}
+	for _, metric := range projectDeltaMetrics {
+		headers = append(headers, metric.Name+" (old)", metric.Name+" (new)", metric.Name+" (change)")
+	}
+	sheet, created, err := getOrAddSheet(wbk, "Project Changes")
+	if err != nil {
+		return err
+	}
+	if created {
+		writeHeaderRow(headers, sheet)
+	}
+	regression := regressionStyle()
+	for _, project := range delta.Projects {
+		row := sheet.AddRow()
+		row.AddCell().SetString(project.ProjectName)
+		row.AddCell().SetString(project.Status)
+		for _, value := range project.Values {
+			setDeltaCell(row.AddCell(), value.Old)
+			setDeltaCell(row.AddCell(), value.New)
+			cell := row.AddCell()
+			if change, ok := value.change(); ok {
+				cell.SetInt64(change)
+			} else {
+				cell.SetString("-")
+			}
+			if value.regression() {
+				cell.SetStyle(regression)
+			}
+		}
+	}
+	autoSizeSheet(sheet)
+	return nil
+}
+
+// write the edits that became used, newly unused or were removed
+func writeEditChanges(delta RunDelta, wbk *xlsx.File) error {
+	headers := []string{"Project Name",
+		"Edit Check Name",
+		"Form OID",
+		"Field OID",
+		"Variable OID",
+		"OpenQuery?",
+		"Change",
+	}
+	sheet, created, err := getOrAddSheet(wbk, "Edit Changes")
+	if err != nil {
+		return err
+	}
+	if created {
+		writeHeaderRow(headers, sheet)
+	}
+	regression := regressionStyle()
+	for _, edit := range delta.Edits {
+		row := sheet.AddRow()
+		row.AddCell().SetString(edit.ProjectName)
+		row.AddCell().SetString(edit.EditCheckName)
+		row.AddCell().SetString(edit.FormOID)
+		row.AddCell().SetString(edit.FieldOID)
+		row.AddCell().SetString(edit.VariableOID)
+		cell := row.AddCell()
+		if edit.OpenQuery {
+			cell.SetString("Y")
+		} else {
+			cell.SetString("N")
+		}
+		cell = row.AddCell()
+		switch {
+		case edit.NowUsed:
+			cell.SetString("Now used")
+		case edit.Removed:
+			cell.SetString("Removed")
+		default:
+			cell.SetString("Newly unused")
+			for _, regressed := range row.Cells {
+				regressed.SetStyle(regression)
+			}
+		}
+	}
+	autoSizeSheet(sheet)
+	return nil
+}
+
+// write the comparison of two runs
+func writeRunDelta(delta RunDelta, wbk *xlsx.File) error {
+	if err := writeRunComparison(delta, wbk); err != nil {
+		return err
+	}
+	if err := writeProjectChanges(delta, wbk); err != nil {
+		return err
+	}
+	return writeEditChanges(delta, wbk)
+}
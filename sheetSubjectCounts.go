@@ -5,7 +5,7 @@ import (
 )
 
 // write the Subject Counts
-func writeSubjectCount(urlName string, projects []*Project, wbk *xlsx.File) {
+func writeSubjectCount(urlName string, projects []*Project, wbk *xlsx.File) error {
 	tabName := "Subject Counts"
 	headers := []string{"Rave URL",
 		"Project Name",
@@ -20,7 +20,10 @@ func writeSubjectCount(urlName string, projects []*Project, wbk *xlsx.File) {
 	// maxWidth is an array of column widths
 	var maxWidth = initColumns(headers)
 	// create the sheet
-	sheet, created := getOrAddSheet(wbk, tabName)
+	sheet, created, err := getOrAddSheet(wbk, tabName)
+	if err != nil {
+		return err
+	}
 	if created {
 		// Add the headers if it's newly created
 		writeHeaderRow(headers, sheet)
@@ -128,4 +131,5 @@ func writeSubjectCount(urlName string, projects []*Project, wbk *xlsx.File) {
 	}
 	// resize the sheet
 	autoSizeSheet(sheet)
+	return nil
 }
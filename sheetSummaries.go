@@ -1,12 +1,11 @@
 package main
 
 import (
-	"fmt"
 	"github.com/tealeg/xlsx"
 )
 
-// Write the aggregated averages, broken down by the threshold
-func writeAggregatedCounts(agg AggregateCount, sheet *xlsx.Sheet) {
+// Write the aggregated averages, broken down by cohort
+func writeAggregatedCounts(cohorts []CohortCounts, sheet *xlsx.Sheet) {
 	// write the averages
 	headers := []string{"Criteria",
 		"Aggregate",
@@ -36,22 +35,13 @@ func writeAggregatedCounts(agg AggregateCount, sheet *xlsx.Sheet) {
 		"%ge Checks with No Change (prg)",
 	}
 	writeHeaderRow(headers, sheet)
-	var summary SummaryCounts
-	// All Projects
-	summary = agg.AllProjects
-	// no studies above the threshold
-	writeAggregates("All Projects", sheet, summary)
-	// Greater than 10 subjects
-	summary = agg.GreaterThanTen
-	// no studies above the threshold
-	writeAggregates("Subject Count", sheet, summary)
-	// Completed Subjects
-	summary = agg.CompletedSubjects
-	// no studies above the threshold
-	writeAggregates("Completed Subjects", sheet, summary)
+	for _, cohort := range cohorts {
+		// no rows for an empty cohort
+		writeAggregates(cohort.Name, cohort.Criteria, sheet, cohort.Counts)
+	}
 }
 
-func writeAggregates(description string, sheet *xlsx.Sheet, summary SummaryCounts) {
+func writeAggregates(description, criteria string, sheet *xlsx.Sheet, summary SummaryCounts) {
 	var cell *xlsx.Cell
 	// check if there are any records
 	if summary.RecordCount > 0 {
@@ -65,11 +55,7 @@ func writeAggregates(description string, sheet *xlsx.Sheet, summary SummaryCount
 		cell.SetString("Sum")
 		// Threshold
 		cell = row.AddCell()
-		if summary.Threshold > 0 {
-			cell.SetString(fmt.Sprintf("> %d", summary.Threshold))
-		} else {
-			cell.SetString("ALL")
-		}
+		cell.SetString(criteria)
 		writeSumSummaryCounts(row, summary)
 	}
 	avg := summary.getAverageCounts()
@@ -86,11 +72,7 @@ func writeAggregates(description string, sheet *xlsx.Sheet, summary SummaryCount
 		cell.SetString("Average")
 		// Threshold
 		cell = row.AddCell()
-		if summary.Threshold > 0 {
-			cell.SetString(fmt.Sprintf("> %d", summary.Threshold))
-		} else {
-			cell.SetString("ALL")
-		}
+		cell.SetString(criteria)
 
 		writeAvgSummaryCounts(row, summary.getAverageCounts())
 	}
@@ -172,7 +154,7 @@ func writeSumSummaryCounts(row *xlsx.Row, summary SummaryCounts) {
 	cell.SetInt(summary.TotalPrgEditsOpen)
 	// Percentage Prog Edit Fired
 	cell = row.AddCell()
-	if summary.TotalPrgEdits > 0 {
+	if summary.TotalPrgEditsWithOpenQuery > 0 {
 		cell.SetFloatWithFormat(float64(summary.TotalPrgEditsFired)/float64(summary.TotalPrgEditsWithOpenQuery),
 			"0.00%")
 	} else {
@@ -180,7 +162,7 @@ func writeSumSummaryCounts(row *xlsx.Row, summary SummaryCounts) {
 	}
 	// Percentage Prog Edit Not Fired
 	cell = row.AddCell()
-	if summary.TotalPrgEdits > 0 {
+	if summary.TotalPrgEditsWithOpenQuery > 0 {
 		cell.SetFloatWithFormat(float64(summary.TotalPrgEditsUnfired)/float64(summary.TotalPrgEditsWithOpenQuery),
 			"0.00%")
 	} else {
@@ -351,83 +333,14 @@ func writeAvgSummaryCounts(row *xlsx.Row, summary AverageSummaryCounts) {
 //
 //}
 
-// Write the summary counts (Average and Sum) for a Last Project Version Sheet
-func writeSummaryCounts(projects []*Project, wbk *xlsx.File) {
-	const subjectThreshold = 10
-	const completedCount = 1
-	// Count holders
-	var aggregateCount AggregateCount
-	// initiate values
-	aggregateCount.GreaterThanTen.RecordCount = 0
-	aggregateCount.AllProjects.RecordCount = 0
-	aggregateCount.CompletedSubjects.RecordCount = 0
-
-	// Scan the projects
-	for _, project := range projects {
-		lastProjectVersion := project.getLastVersion()
-		// filtered set of counts
-		if project.SubjectCount.SubjectCount > subjectThreshold {
-			//log.Println("Adding counts for ", last_project_version.ProjectName,"with count",last_project_version.SubjectCount, "with threshold",threshold)
-			aggregateCount.GreaterThanTen.RecordCount++
-			aggregateCount.GreaterThanTen.Threshold = subjectThreshold
-			aggregateCount.GreaterThanTen.SubjectCount += project.SubjectCount.SubjectCount
-			aggregateCount.GreaterThanTen.TotalEdits += lastProjectVersion.getTotalEdits()
-			aggregateCount.GreaterThanTen.TotalFldEdits += lastProjectVersion.FieldEditMetrics.TotalEdits
-			aggregateCount.GreaterThanTen.TotalFldEditsFired += lastProjectVersion.FieldEditMetrics.TotalFiredWithOpenQuery
-			aggregateCount.GreaterThanTen.TotalFldEditsUnfired += lastProjectVersion.FieldEditMetrics.TotalNotFiredWithOpenQuery
-			aggregateCount.GreaterThanTen.TotalFldEditsOpen += lastProjectVersion.FieldEditMetrics.TotalOpenQueries
-			aggregateCount.GreaterThanTen.TotalFldWithChange += lastProjectVersion.FieldEditMetrics.TotalEditsFiredWithChange
-			aggregateCount.GreaterThanTen.TotalFldWithNoChange += lastProjectVersion.FieldEditMetrics.TotalEditsFiredWithNoChange
-			aggregateCount.GreaterThanTen.TotalPrgEdits += lastProjectVersion.ProgramEditMetrics.TotalEdits
-			aggregateCount.GreaterThanTen.TotalPrgEditsWithOpenQuery += lastProjectVersion.ProgramEditMetrics.TotalEditsWithOpenQuery
-			aggregateCount.GreaterThanTen.TotalPrgEditsFired += lastProjectVersion.ProgramEditMetrics.TotalFiredWithOpenQuery
-			aggregateCount.GreaterThanTen.TotalPrgEditsUnfired += lastProjectVersion.ProgramEditMetrics.TotalNotFiredWithOpenQuery
-			aggregateCount.GreaterThanTen.TotalPrgEditsOpen += lastProjectVersion.ProgramEditMetrics.TotalOpenQueries
-			aggregateCount.GreaterThanTen.TotalPrgWithChange += lastProjectVersion.ProgramEditMetrics.TotalEditsFiredWithChange
-			aggregateCount.GreaterThanTen.TotalPrgWithNoChange += lastProjectVersion.ProgramEditMetrics.TotalEditsFiredWithNoChange
-		}
-		// Check for completedSubjects
-		if project.SubjectCount.CompletedCount.Valid {
-			if project.SubjectCount.CompletedCount.Int64 > completedCount {
-				aggregateCount.CompletedSubjects.RecordCount++
-				aggregateCount.CompletedSubjects.Threshold = subjectThreshold
-				aggregateCount.CompletedSubjects.SubjectCount += project.SubjectCount.SubjectCount
-				aggregateCount.CompletedSubjects.TotalEdits += lastProjectVersion.getTotalEdits()
-				aggregateCount.CompletedSubjects.TotalFldEdits += lastProjectVersion.FieldEditMetrics.TotalEdits
-				aggregateCount.CompletedSubjects.TotalFldEditsFired += lastProjectVersion.FieldEditMetrics.TotalFiredWithOpenQuery
-				aggregateCount.CompletedSubjects.TotalFldEditsUnfired += lastProjectVersion.FieldEditMetrics.TotalNotFiredWithOpenQuery
-				aggregateCount.CompletedSubjects.TotalFldEditsOpen += lastProjectVersion.FieldEditMetrics.TotalOpenQueries
-				aggregateCount.CompletedSubjects.TotalFldWithChange += lastProjectVersion.FieldEditMetrics.TotalEditsFiredWithChange
-				aggregateCount.CompletedSubjects.TotalFldWithNoChange += lastProjectVersion.FieldEditMetrics.TotalEditsFiredWithNoChange
-				aggregateCount.CompletedSubjects.TotalPrgEdits += lastProjectVersion.ProgramEditMetrics.TotalEdits
-				aggregateCount.CompletedSubjects.TotalPrgEditsWithOpenQuery += lastProjectVersion.ProgramEditMetrics.TotalEditsWithOpenQuery
-				aggregateCount.CompletedSubjects.TotalPrgEditsFired += lastProjectVersion.ProgramEditMetrics.TotalFiredWithOpenQuery
-				aggregateCount.CompletedSubjects.TotalPrgEditsUnfired += lastProjectVersion.ProgramEditMetrics.TotalNotFiredWithOpenQuery
-				aggregateCount.CompletedSubjects.TotalPrgEditsOpen += lastProjectVersion.ProgramEditMetrics.TotalOpenQueries
-				aggregateCount.CompletedSubjects.TotalPrgWithChange += lastProjectVersion.ProgramEditMetrics.TotalEditsFiredWithChange
-				aggregateCount.CompletedSubjects.TotalPrgWithNoChange += lastProjectVersion.ProgramEditMetrics.TotalEditsFiredWithNoChange
-			}
-		}
-		// All Subjects
-		aggregateCount.AllProjects.RecordCount++
-		aggregateCount.AllProjects.Threshold = subjectThreshold
-		aggregateCount.AllProjects.SubjectCount += project.SubjectCount.SubjectCount
-		aggregateCount.AllProjects.TotalEdits += lastProjectVersion.getTotalEdits()
-		aggregateCount.AllProjects.TotalFldEdits += lastProjectVersion.FieldEditMetrics.TotalEdits
-		aggregateCount.AllProjects.TotalFldEditsFired += lastProjectVersion.FieldEditMetrics.TotalFiredWithOpenQuery
-		aggregateCount.AllProjects.TotalFldEditsUnfired += lastProjectVersion.FieldEditMetrics.TotalNotFiredWithOpenQuery
-		aggregateCount.AllProjects.TotalFldEditsOpen += lastProjectVersion.FieldEditMetrics.TotalOpenQueries
-		aggregateCount.AllProjects.TotalFldWithChange += lastProjectVersion.FieldEditMetrics.TotalEditsFiredWithChange
-		aggregateCount.AllProjects.TotalFldWithNoChange += lastProjectVersion.FieldEditMetrics.TotalEditsFiredWithNoChange
-		aggregateCount.AllProjects.TotalPrgEdits += lastProjectVersion.ProgramEditMetrics.TotalEdits
-		aggregateCount.AllProjects.TotalPrgEditsWithOpenQuery += lastProjectVersion.ProgramEditMetrics.TotalEditsWithOpenQuery
-		aggregateCount.AllProjects.TotalPrgEditsFired += lastProjectVersion.ProgramEditMetrics.TotalFiredWithOpenQuery
-		aggregateCount.AllProjects.TotalPrgEditsUnfired += lastProjectVersion.ProgramEditMetrics.TotalNotFiredWithOpenQuery
-		aggregateCount.AllProjects.TotalPrgEditsOpen += lastProjectVersion.ProgramEditMetrics.TotalOpenQueries
-		aggregateCount.AllProjects.TotalPrgWithChange += lastProjectVersion.ProgramEditMetrics.TotalEditsFiredWithChange
-		aggregateCount.AllProjects.TotalPrgWithNoChange += lastProjectVersion.ProgramEditMetrics.TotalEditsFiredWithNoChange
+// the name of the summary counts sheet, labelled for the inactive edits
+func summaryCountsSheetName(status EditStatusFilter) string {
+	return "Summary Counts" + status.sheetSuffix()
+}
 
-	}
+// Write the summary counts (Average and Sum) for a Last Project Version Sheet
+func writeSummaryCounts(projects []*Project, cohorts []Cohort, status EditStatusFilter, wbk *xlsx.File) error {
+	cohortCounts := summarizeCohorts(projects, cohorts, status)
 
 	//headers := []string{
 	//	"Criteria",
@@ -447,9 +360,12 @@ func writeSummaryCounts(projects []*Project, wbk *xlsx.File) {
 	//	"Checks Leading to Change",
 	//	"Checks Not Leading to Change",
 	//}
-	sheet, _ := getOrAddSheet(wbk, "Summary Counts")
+	sheet, _, err := getOrAddSheet(wbk, summaryCountsSheetName(status))
+	if err != nil {
+		return err
+	}
 	// write the counts out
-	writeAggregatedCounts(aggregateCount, sheet)
+	writeAggregatedCounts(cohortCounts, sheet)
 	//	writeNotes(sheet)
 	// filter project -> subject count
 	autoFilter := new(xlsx.AutoFilter)
@@ -457,4 +373,5 @@ func writeSummaryCounts(projects []*Project, wbk *xlsx.File) {
 	autoFilter.BottomRightCell = "E1"
 	sheet.AutoFilter = autoFilter
 	autoSizeSheet(sheet)
+	return nil
 }
@@ -2,10 +2,9 @@ package main
 
 import (
 	"github.com/tealeg/xlsx"
-	"strings"
 )
 
-func writeUselessEdits(projectName string, edits []*UnusedEdit, checkOutcome EditCheckOutcome, wbk *xlsx.File) {
+func writeUselessEdits(projectName string, edits []*UnusedEdit, checkOutcome EditCheckOutcome, rules *ClassificationRules, wbk *xlsx.File) error {
 	headers := []string{"Project Name",
 		"Edit Check Name",
 		"Form OID",
@@ -13,10 +12,11 @@ func writeUselessEdits(projectName string, edits []*UnusedEdit, checkOutcome Edi
 		"Variable OID",
 		"Times Used",
 		"Custom Function?",
-		"Non-conformance check?",
-		"Required check?",
-		"Future check?",
-		"Range check?",
+	}
+	// a column per flag, eg Required check?
+	categories := rules.flagCategories()
+	for _, category := range categories {
+		headers = append(headers, category+" check?")
 	}
 	var tabName string
 	if checkOutcome == OpenQuery {
@@ -27,13 +27,16 @@ func writeUselessEdits(projectName string, edits []*UnusedEdit, checkOutcome Edi
 		//log.Println("Printing", len(edits), "edits without OpenQuery")
 	}
 	// create the sheet
-	sheet, created := getOrAddSheet(wbk, tabName)
+	sheet, created, err := getOrAddSheet(wbk, tabName)
+	if err != nil {
+		return err
+	}
 	if created {
 		// Add the headers
 		colWidths := writeHeaderRow(headers, sheet)
 		autoFilter := new(xlsx.AutoFilter)
 		autoFilter.TopLeftCell = "A1"
-		autoFilter.BottomRightCell = "M1"
+		autoFilter.BottomRightCell = xlsx.GetCellIDStringFromCoords(len(headers)-1, 0)
 		sheet.AutoFilter = autoFilter
 		for idx, width := range colWidths {
 			_ = sheet.SetColWidth(idx, idx, width)
@@ -115,35 +118,15 @@ func writeUselessEdits(projectName string, edits []*UnusedEdit, checkOutcome Edi
 		} else {
 			cell.SetString("N")
 		}
-		cell = row.AddCell()
-		// Non-conformant
-		if strings.HasPrefix(edit.EditCheckName, "SYS_NC_") {
-			cell.SetString("Y")
-		} else {
-			cell.SetString("N")
-		}
-		cell = row.AddCell()
-		// cell.SetString(edit.RequiredCheck)
-		if strings.HasPrefix(edit.EditCheckName, "SYS_REQ_") {
-			cell.SetString("Y")
-		} else {
-			cell.SetString("N")
-		}
-		cell = row.AddCell()
-		// Future Date Check
-		if strings.HasPrefix(edit.EditCheckName, "SYS_FUTURE_") {
-			cell.SetString("Y")
-		} else {
-			cell.SetString("N")
-		}
-		cell = row.AddCell()
-		// Range Check
-		if strings.HasPrefix(edit.EditCheckName, "SYS_Q_RANGE_") {
-			cell.SetString("Y")
-		} else {
-			cell.SetString("N")
+		// Non-conformant, Required, Future Date, Range, etc
+		for _, category := range categories {
+			cell = row.AddCell()
+			if rules.hasFlag(category, edit.EditCheckName, edit.Actions) {
+				cell.SetString("Y")
+			} else {
+				cell.SetString("N")
+			}
 		}
-		// cell.SetString(edit.RangeCheck)
 	}
 	//sheet.SetColWidth(0, 0, float64(projectLength))
 	//sheet.SetColWidth(1, 1, float64(checkLength))
@@ -151,4 +134,5 @@ func writeUselessEdits(projectName string, edits []*UnusedEdit, checkOutcome Edi
 	//sheet.SetColWidth(3, 3, float64(fieldOIDLength))
 	//sheet.SetColWidth(4, 4, float64(vblOIDLength))
 
+	return nil
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/tealeg/xlsx"
+)
+
+// write the edit checks that changed between consecutive versions of a project
+func writeVersionDiffForProject(project *Project, wbk *xlsx.File) error {
+	tabName := "Version Diff"
+	headers := []string{"Project Name",
+		"From Version",
+		"To Version",
+		"Edit Check Name",
+		"Change",
+		"Form OID (from)",
+		"Form OID (to)",
+		"Field OID (from)",
+		"Field OID (to)",
+		"Variable OID (from)",
+		"Variable OID (to)",
+		"Actions (from)",
+		"Actions (to)",
+	}
+	// create the sheet
+	sheet, created, err := getOrAddSheet(wbk, tabName)
+	if err != nil {
+		return err
+	}
+	if created {
+		// Add the headers if it's newly created
+		writeHeaderRow(headers, sheet)
+		autoFilter := new(xlsx.AutoFilter)
+		autoFilter.TopLeftCell = "A1"
+		autoFilter.BottomRightCell = "M1"
+		sheet.AutoFilter = autoFilter
+	}
+	// the value from a definition, empty when the check isn't in the version
+	definitionValue := func(definition *editCheckDefinition, value func(editCheckDefinition) string) string {
+		if definition == nil {
+			return ""
+		}
+		return value(*definition)
+	}
+	for _, change := range project.getVersionChanges() {
+		row := sheet.AddRow()
+		row.AddCell().SetString(project.ProjectName)
+		row.AddCell().SetInt(change.FromVersion)
+		row.AddCell().SetInt(change.ToVersion)
+		row.AddCell().SetString(change.EditCheckName)
+		row.AddCell().SetString(change.description())
+		for _, value := range []func(editCheckDefinition) string{
+			func(definition editCheckDefinition) string { return definition.FormOIDs },
+			func(definition editCheckDefinition) string { return definition.FieldOIDs },
+			func(definition editCheckDefinition) string { return definition.VariableOIDs },
+			func(definition editCheckDefinition) string { return definition.Actions },
+		} {
+			row.AddCell().SetString(definitionValue(change.Old, value))
+			row.AddCell().SetString(definitionValue(change.New, value))
+		}
+	}
+	autoSizeSheet(sheet)
+	return nil
+}
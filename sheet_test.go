@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/tealeg/xlsx"
+)
+
+func TestSheetName(t *testing.T) {
+	long := strings.Repeat("p", 40)
+	tests := []struct {
+		prefix   string
+		suffix   string
+		expected string
+	}{
+		{"pharma", "", "pharma"},
+		{"pharma", " - Last", "pharma - Last"},
+		{long, "", strings.Repeat("p", 31)},
+		{long, " - Last", strings.Repeat("p", 24) + " - Last"},
+		{strings.Repeat("é", 40), " - Last", strings.Repeat("é", 24) + " - Last"},
+		{"pharma", strings.Repeat("s", 40), strings.Repeat("s", 31)},
+	}
+	for _, test := range tests {
+		got := sheetName(test.prefix, test.suffix)
+		if got != test.expected {
+			t.Errorf("sheetName(%q, %q): expected %q, got %q", test.prefix, test.suffix, test.expected, got)
+		}
+		if length := utf8.RuneCountInString(got); length > MaxSheetNameLength {
+			t.Errorf("sheetName(%q, %q) is %d characters", test.prefix, test.suffix, length)
+		}
+	}
+}
+
+func TestGetOrAddSheet(t *testing.T) {
+	wbk := xlsx.NewFile()
+	long := strings.Repeat("p", 40)
+	sheet, created, err := getOrAddSheet(wbk, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || sheet.Name != strings.Repeat("p", 31) {
+		t.Errorf("expected a new sheet with a shortened name, got %q (created %v)", sheet.Name, created)
+	}
+	again, created, err := getOrAddSheet(wbk, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || again != sheet {
+		t.Error("expected the existing sheet for the same long name")
+	}
+	if _, _, err := getOrAddSheet(wbk, "Unused/Edits"); err == nil {
+		t.Error("expected an error for a name with a restricted character")
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import "context"
+
+// Store is the source of the BodyCheck data used to build a report
+type Store interface {
+	// get RaveURLS that match the pattern
+	GetURLsThatMatch(ctx context.Context, matcher *URLMatcher) ([]RaveURL, error)
+	// dump a list of URLs
+	listURLs(ctx context.Context) ([]string, error)
+	// get the Projects for a URL
+	getProjects(ctx context.Context, urlID int) ([]*Project, error)
+	// Get the project versions
+	getProjectVersions(ctx context.Context, projectID int) ([]*ProjectVersion, error)
+	// get the Subject Counts for all the projects in a URL
+	getSubjectCounts(ctx context.Context, urlID int) ([]SubjectCount, error)
+	// get the Subject Count for a single project
+	getProjectSubjectCount(ctx context.Context, urlID, projectID int) (SubjectCount, error)
+	// get the edits that have never been used
+	getUselessEditsForProject(ctx context.Context, projectID int, withOpenQueryFilter EditCheckOutcome) ([]*UnusedEdit, error)
+	// get the summary by type for every version of every project in a URL, over the active,
+	// inactive or all edit checks
+	getVersionMetricsForURL(ctx context.Context, urlID int) ([]VersionEditTypeMetric, error)
+	// get the counts by edit check status for every version of every project in a URL
+	getActivityCountsForURL(ctx context.Context, urlID int) ([]VersionStatusCounts, error)
+	// get the edit checks for every version of every project in a URL
+	getEditChecksForURL(ctx context.Context, urlID int) ([]EditCheck, error)
+	// get the distinct edit check names of every project in a URL
+	getEditCheckNamesForURL(ctx context.Context, urlID int) ([]ProjectEditCheckName, error)
+}
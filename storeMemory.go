@@ -0,0 +1,518 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// fixtureURL mirrors a rave_url row
+type fixtureURL struct {
+	URLID        int    `json:"id"`
+	URL          string `json:"url"`
+	AlternateURL string `json:"alternate_url"`
+}
+
+// fixtureProject mirrors a project row
+type fixtureProject struct {
+	ProjectID   int    `json:"id"`
+	URLID       int    `json:"url_id"`
+	ProjectName string `json:"project_name"`
+}
+
+// fixtureLastVersion mirrors a project_last_version row
+type fixtureLastVersion struct {
+	ProjectID    int `json:"project_id"`
+	CRFVersionID int `json:"crf_version_id"`
+}
+
+// fixtureRefreshDate mirrors a refresh_date row
+type fixtureRefreshDate struct {
+	ProjectID   int       `json:"project_id"`
+	RefreshDate time.Time `json:"refresh_date"`
+}
+
+// fixtureEditCheck mirrors an edit_check row; empty OIDs stand in for NULL
+type fixtureEditCheck struct {
+	URLID                    int    `json:"url_id"`
+	ProjectID                int    `json:"project_id"`
+	CRFVersionID             int    `json:"crf_version_id"`
+	EditCheckName            string `json:"edit_check_name"`
+	FormOID                  string `json:"form_oid"`
+	FieldOID                 string `json:"field_oid"`
+	VariableOID              string `json:"variable_oid"`
+	Actions                  string `json:"actions"`
+	IsActive                 int    `json:"is_active"`
+	TotalCheckExecutions     int    `json:"total_check_executions"`
+	OpenChecks               int    `json:"open_checks"`
+	ChangeCount              int    `json:"change_count"`
+	NoChangeCount            int    `json:"no_change_count"`
+	SubjectCount             int    `json:"subject_count"`
+	ScreeningSubjects        *int64 `json:"screening_subjects"`
+	ScreeningFailureSubjects *int64 `json:"screening_failure_subjects"`
+	EnrolledSubjects         *int64 `json:"enrolled_subjects"`
+	CompletedSubjects        *int64 `json:"completed_subjects"`
+	EnrolledFollowUpSubjects *int64 `json:"enrolled_follow_up_subjects"`
+	EarlyTerminatedSubjects  *int64 `json:"early_terminated_subjects"`
+}
+
+// MemoryStore is a Store backed by an in-memory copy of the BodyCheck tables
+type MemoryStore struct {
+	URLs         []fixtureURL         `json:"rave_url"`
+	Projects     []fixtureProject     `json:"project"`
+	LastVersions []fixtureLastVersion `json:"project_last_version"`
+	RefreshDates []fixtureRefreshDate `json:"refresh_date"`
+	EditChecks   []fixtureEditCheck   `json:"edit_check"`
+	// how the edit checks are classified
+	rules *ClassificationRules
+}
+
+// load a MemoryStore from a JSON fixture file
+func newMemoryStore(fileName string, rules *ClassificationRules) (*MemoryStore, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	store := &MemoryStore{rules: rules}
+	if err := json.Unmarshal(content, store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// GetURLsThatMatch returns the RaveURLs where either URL matches
+func (s *MemoryStore) GetURLsThatMatch(ctx context.Context, matcher *URLMatcher) (urls []RaveURL, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	for _, url := range s.URLs {
+		raveURL := RaveURL{
+			PreferredURL: url.URL,
+			URLID:        url.URLID,
+			AlternateURL: url.AlternateURL,
+		}
+		if matcher.matchesRaveURL(raveURL) {
+			urls = append(urls, raveURL)
+		}
+	}
+	return
+}
+
+// dump a list of URLs
+func (s *MemoryStore) listURLs(ctx context.Context) (urls []string, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	ordered := make([]fixtureURL, len(s.URLs))
+	copy(ordered, s.URLs)
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].URL != ordered[j].URL {
+			return ordered[i].URL < ordered[j].URL
+		}
+		return ordered[i].AlternateURL < ordered[j].AlternateURL
+	})
+	for _, url := range ordered {
+		if url.AlternateURL != "" {
+			urls = append(urls, url.AlternateURL)
+		} else {
+			urls = append(urls, url.URL)
+		}
+	}
+	return
+}
+
+// get the Projects for a URL
+func (s *MemoryStore) getProjects(ctx context.Context, urlID int) (projects []*Project, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	for _, prj := range s.Projects {
+		if prj.URLID == urlID {
+			projects = append(projects, &Project{
+				URLID:       prj.URLID,
+				ProjectID:   prj.ProjectID,
+				ProjectName: prj.ProjectName,
+			})
+		}
+	}
+	return
+}
+
+// get the last CRF Version for a project, if there is one
+func (s *MemoryStore) getLastVersion(projectID int) (int, bool) {
+	for _, plv := range s.LastVersions {
+		if plv.ProjectID == projectID {
+			return plv.CRFVersionID, true
+		}
+	}
+	return 0, false
+}
+
+// Get the project versions
+func (s *MemoryStore) getProjectVersions(ctx context.Context, projectID int) (projectVersions []*ProjectVersion, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	lastVersion, hasLastVersion := s.getLastVersion(projectID)
+	seen := make(map[int]bool)
+	for _, edt := range s.EditChecks {
+		if edt.ProjectID != projectID || seen[edt.CRFVersionID] {
+			continue
+		}
+		seen[edt.CRFVersionID] = true
+		projectVersions = append(projectVersions, &ProjectVersion{
+			ProjectID:    projectID,
+			CRFVersionID: edt.CRFVersionID,
+			LastVersion:  hasLastVersion && lastVersion == edt.CRFVersionID,
+		})
+	}
+	return
+}
+
+// take the larger of a running MAX() and a nullable value
+func maxNullInt64(current sql.NullInt64, value *int64) sql.NullInt64 {
+	if value == nil {
+		return current
+	}
+	if !current.Valid || *value > current.Int64 {
+		return sql.NullInt64{Int64: *value, Valid: true}
+	}
+	return current
+}
+
+// build the subject count for a project, one per refresh date
+func (s *MemoryStore) subjectCountsForProject(urlID int, project fixtureProject) (subjectCounts []SubjectCount) {
+	var counts SubjectCount
+	found := false
+	for _, edt := range s.EditChecks {
+		if edt.URLID != urlID || edt.ProjectID != project.ProjectID {
+			continue
+		}
+		if !found || edt.SubjectCount > counts.SubjectCount {
+			counts.SubjectCount = edt.SubjectCount
+		}
+		found = true
+		counts.ScreeningCount = maxNullInt64(counts.ScreeningCount, edt.ScreeningSubjects)
+		counts.ScreeningFailureCount = maxNullInt64(counts.ScreeningFailureCount, edt.ScreeningFailureSubjects)
+		counts.EnrolledCount = maxNullInt64(counts.EnrolledCount, edt.EnrolledSubjects)
+		counts.CompletedCount = maxNullInt64(counts.CompletedCount, edt.CompletedSubjects)
+		counts.FollowUpCount = maxNullInt64(counts.FollowUpCount, edt.EnrolledFollowUpSubjects)
+		counts.EarlyTerminatedCount = maxNullInt64(counts.EarlyTerminatedCount, edt.EarlyTerminatedSubjects)
+	}
+	if !found {
+		return
+	}
+	counts.URLID = urlID
+	counts.ProjectID = project.ProjectID
+	counts.ProjectName = project.ProjectName
+	// inner join onto the refresh dates
+	for _, rd := range s.RefreshDates {
+		if rd.ProjectID == project.ProjectID {
+			counts.RefreshDate = pq.NullTime{Time: rd.RefreshDate, Valid: true}
+			subjectCounts = append(subjectCounts, counts)
+		}
+	}
+	return
+}
+
+// get the Subject Counts for all the projects in a URL
+func (s *MemoryStore) getSubjectCounts(ctx context.Context, urlID int) (subjectCounts []SubjectCount, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	for _, prj := range s.Projects {
+		subjectCounts = append(subjectCounts, s.subjectCountsForProject(urlID, prj)...)
+	}
+	return
+}
+
+// get the Subject Count for a single project
+func (s *MemoryStore) getProjectSubjectCount(ctx context.Context, urlID, projectID int) (subjectCount SubjectCount, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	for _, prj := range s.Projects {
+		if prj.ProjectID != projectID {
+			continue
+		}
+		for _, counts := range s.subjectCountsForProject(urlID, prj) {
+			subjectCount = counts
+		}
+	}
+	return
+}
+
+// the distinct, non-empty values of the edit checks, pipe separated
+func distinctEditCheckValues(editChecks []fixtureEditCheck, value func(edt fixtureEditCheck) string) string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, edt := range editChecks {
+		if v := value(edt); v != "" && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return strings.Join(values, "|")
+}
+
+// the distinct OIDs across every edit check with the name, pipe separated
+func (s *MemoryStore) getOIDs(editCheckName string, oid func(edt fixtureEditCheck) string) string {
+	var editChecks []fixtureEditCheck
+	for _, edt := range s.EditChecks {
+		if edt.EditCheckName == editCheckName {
+			editChecks = append(editChecks, edt)
+		}
+	}
+	return distinctEditCheckValues(editChecks, oid)
+}
+
+// the distinct actions of the edit checks with the name in the project, pipe separated
+func (s *MemoryStore) getActions(projectID int, editCheckName string) string {
+	var editChecks []fixtureEditCheck
+	for _, edt := range s.EditChecks {
+		if edt.ProjectID == projectID && edt.EditCheckName == editCheckName {
+			editChecks = append(editChecks, edt)
+		}
+	}
+	return distinctEditCheckValues(editChecks, func(edt fixtureEditCheck) string { return edt.Actions })
+}
+
+// get the edits that have never been used
+func (s *MemoryStore) getUselessEditsForProject(ctx context.Context, projectID int, withOpenQueryFilter EditCheckOutcome) (unusedEdits []*UnusedEdit, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	totalCount := make(map[string]int)
+	totalExecutions := make(map[string]int)
+	customFunction := make(map[string]bool)
+	var names []string
+	for _, edt := range s.EditChecks {
+		if edt.ProjectID != projectID {
+			continue
+		}
+		// any version of the check calling a CustomFunction
+		if strings.Contains(edt.Actions, "CustomFunction") {
+			customFunction[edt.EditCheckName] = true
+		}
+		if strings.Contains(edt.Actions, "OpenQuery") != (withOpenQueryFilter == OpenQuery) {
+			continue
+		}
+		if _, ok := totalCount[edt.EditCheckName]; !ok {
+			names = append(names, edt.EditCheckName)
+		}
+		totalCount[edt.EditCheckName]++
+		totalExecutions[edt.EditCheckName] += edt.TotalCheckExecutions
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if totalExecutions[name] != 0 {
+			continue
+		}
+		unusedEdits = append(unusedEdits, &UnusedEdit{
+			ProjectID:      projectID,
+			EditCheckName:  name,
+			FormOID:        s.getOIDs(name, func(edt fixtureEditCheck) string { return edt.FormOID }),
+			FieldOID:       s.getOIDs(name, func(edt fixtureEditCheck) string { return edt.FieldOID }),
+			VariableOID:    s.getOIDs(name, func(edt fixtureEditCheck) string { return edt.VariableOID }),
+			Actions:        s.getActions(projectID, name),
+			UsageCount:     totalCount[name],
+			CustomFunction: customFunction[name],
+		})
+	}
+	return
+}
+
+// accumulate into a SUM() column
+func addNullInt64(total *sql.NullInt64, value int) {
+	total.Int64 += int64(value)
+	total.Valid = true
+}
+
+// get the URL a project belongs to
+func (s *MemoryStore) getProjectURLID(projectID int) (int, bool) {
+	for _, prj := range s.Projects {
+		if prj.ProjectID == projectID {
+			return prj.URLID, true
+		}
+	}
+	return 0, false
+}
+
+// add an edit check row to the summary
+func addToMetrics(metrics *EditTypeMetric, edt fixtureEditCheck) {
+	openQuery := strings.Contains(edt.Actions, "OpenQuery")
+	fired := edt.TotalCheckExecutions > 0
+	addNullInt64(&metrics.RawTotalEdits, 1)
+	addNullInt64(&metrics.RawTotalEditsWithOpenQuery, boolToInt(openQuery))
+	addNullInt64(&metrics.RawTotalQueries, edt.TotalCheckExecutions)
+	if edt.OpenChecks >= 0 {
+		addNullInt64(&metrics.RawTotalOpenQueries, edt.OpenChecks)
+	} else {
+		addNullInt64(&metrics.RawTotalOpenQueries, 0)
+	}
+	addNullInt64(&metrics.RawTotalEditsFired, boolToInt(fired))
+	addNullInt64(&metrics.RawTotalEditsNotFired, boolToInt(edt.TotalCheckExecutions == 0))
+	addNullInt64(&metrics.RawTotalFiredWithOpenQuery, boolToInt(fired && openQuery))
+	addNullInt64(&metrics.RawTotalNotFiredWithOpenQuery, boolToInt(edt.TotalCheckExecutions == 0 && openQuery))
+	if openQuery {
+		addNullInt64(&metrics.RawTotalQueriesOpenQuery, edt.TotalCheckExecutions)
+	} else {
+		addNullInt64(&metrics.RawTotalQueriesOpenQuery, 0)
+	}
+	addNullInt64(&metrics.RawTotalEditsFiredWithChange, boolToInt(edt.ChangeCount > 0))
+	addNullInt64(&metrics.RawTotalEditsFiredWithNoChange,
+		boolToInt(edt.ChangeCount == 0 && edt.NoChangeCount > 0))
+	if edt.ChangeCount > 0 {
+		addNullInt64(&metrics.RawTotalQueriesWithChange, edt.ChangeCount)
+	} else {
+		addNullInt64(&metrics.RawTotalQueriesWithChange, 0)
+	}
+	addNullInt64(&metrics.RawTotalOpenEdits, boolToInt(edt.OpenChecks > 0))
+}
+
+// the grouping for the bulk loaded metrics
+type memoryVersionKey struct {
+	projectID    int
+	crfVersionID int
+	checkType    EditCheckClass
+	isActive     int
+}
+
+// get the summary by type for every version of every project in a URL
+func (s *MemoryStore) getVersionMetricsForURL(ctx context.Context, urlID int) (metrics []VersionEditTypeMetric, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	index := make(map[memoryVersionKey]int)
+	for _, edt := range s.EditChecks {
+		if prjURLID, ok := s.getProjectURLID(edt.ProjectID); !ok || prjURLID != urlID || !AllChecks.includes(edt.IsActive) {
+			continue
+		}
+		checkType := Programmed
+		if s.rules.isField(edt.EditCheckName, edt.Actions) {
+			checkType = Field
+		}
+		key := memoryVersionKey{edt.ProjectID, edt.CRFVersionID, checkType, edt.IsActive}
+		idx, ok := index[key]
+		if !ok {
+			idx = len(metrics)
+			index[key] = idx
+			metrics = append(metrics, VersionEditTypeMetric{
+				ProjectID:    edt.ProjectID,
+				CRFVersionID: edt.CRFVersionID,
+				CheckType:    checkType,
+				IsActive:     edt.IsActive,
+			})
+		}
+		addToMetrics(&metrics[idx].EditTypeMetric, edt)
+	}
+	return
+}
+
+// get the counts by edit check status for every version of every project in a URL
+func (s *MemoryStore) getActivityCountsForURL(ctx context.Context, urlID int) (statusCounts []VersionStatusCounts, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	index := make(map[memoryVersionKey]int)
+	for _, edt := range s.EditChecks {
+		if prjURLID, ok := s.getProjectURLID(edt.ProjectID); !ok || prjURLID != urlID {
+			continue
+		}
+		key := memoryVersionKey{projectID: edt.ProjectID, crfVersionID: edt.CRFVersionID}
+		idx, ok := index[key]
+		if !ok {
+			idx = len(statusCounts)
+			index[key] = idx
+			statusCounts = append(statusCounts, VersionStatusCounts{
+				ProjectID:    edt.ProjectID,
+				CRFVersionID: edt.CRFVersionID,
+			})
+		}
+		if edt.IsActive == 1 {
+			statusCounts[idx].ActiveEdits++
+		}
+		if edt.IsActive == 0 {
+			statusCounts[idx].InactiveEdits++
+		}
+	}
+	return
+}
+
+// get the edit checks for every version of every project in a URL
+func (s *MemoryStore) getEditChecksForURL(ctx context.Context, urlID int) (editChecks []EditCheck, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	for _, edt := range s.EditChecks {
+		if prjURLID, ok := s.getProjectURLID(edt.ProjectID); !ok || prjURLID != urlID {
+			continue
+		}
+		editChecks = append(editChecks, EditCheck{
+			ProjectID:            edt.ProjectID,
+			CRFVersionID:         edt.CRFVersionID,
+			EditCheckName:        edt.EditCheckName,
+			FormOID:              edt.FormOID,
+			FieldOID:             edt.FieldOID,
+			VariableOID:          edt.VariableOID,
+			Actions:              edt.Actions,
+			IsActive:             edt.IsActive,
+			TotalCheckExecutions: edt.TotalCheckExecutions,
+			OpenChecks:           edt.OpenChecks,
+			ChangeCount:          edt.ChangeCount,
+			NoChangeCount:        edt.NoChangeCount,
+		})
+	}
+	// ORDER BY project_id, crf_version_id, edit_check_name
+	sort.SliceStable(editChecks, func(i, j int) bool {
+		if editChecks[i].ProjectID != editChecks[j].ProjectID {
+			return editChecks[i].ProjectID < editChecks[j].ProjectID
+		}
+		if editChecks[i].CRFVersionID != editChecks[j].CRFVersionID {
+			return editChecks[i].CRFVersionID < editChecks[j].CRFVersionID
+		}
+		return editChecks[i].EditCheckName < editChecks[j].EditCheckName
+	})
+	return
+}
+
+// the distinct edit check names of every project in a URL
+func (s *MemoryStore) getEditCheckNamesForURL(ctx context.Context, urlID int) (names []ProjectEditCheckName, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	seen := make(map[ProjectEditCheckName]bool)
+	for _, edt := range s.EditChecks {
+		if prjURLID, ok := s.getProjectURLID(edt.ProjectID); !ok || prjURLID != urlID {
+			continue
+		}
+		name := ProjectEditCheckName{ProjectID: edt.ProjectID, EditCheckName: edt.EditCheckName}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	// ORDER BY project_id, edit_check_name
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].ProjectID != names[j].ProjectID {
+			return names[i].ProjectID < names[j].ProjectID
+		}
+		return names[i].EditCheckName < names[j].EditCheckName
+	})
+	return
+}
+
+// CASE WHEN ... THEN 1 ELSE 0 END
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// How a pattern is compared to the Rave URLs
+type URLMatchMode int
+
+const (
+	MatchSubstring URLMatchMode = iota
+	MatchExact
+	MatchGlob
+	MatchRegex
+)
+
+var urlMatchModeNames = []string{"substring", "exact", "glob", "regex"}
+
+func (mode URLMatchMode) String() string {
+	return urlMatchModeNames[mode]
+}
+
+// parse the name of a match mode
+func parseURLMatchMode(name string) (URLMatchMode, error) {
+	for idx, modeName := range urlMatchModeNames {
+		if strings.EqualFold(name, modeName) {
+			return URLMatchMode(idx), nil
+		}
+	}
+	return MatchSubstring, fmt.Errorf("unknown match mode %q, expected one of %s",
+		name, strings.Join(urlMatchModeNames, ", "))
+}
+
+// escape the LIKE metacharacters so they match literally
+func escapeLike(pattern string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(pattern)
+}
+
+// convert a glob (* and ?) into a LIKE pattern
+func globToLike(pattern string) string {
+	var like strings.Builder
+	for _, char := range pattern {
+		switch char {
+		case '*':
+			like.WriteRune('%')
+		case '?':
+			like.WriteRune('_')
+		case '%', '_', '\\':
+			like.WriteRune('\\')
+			like.WriteRune(char)
+		default:
+			like.WriteRune(char)
+		}
+	}
+	return like.String()
+}
+
+// convert a glob (* and ?) into an anchored regular expression
+func globToRegexp(pattern string) string {
+	var expr strings.Builder
+	expr.WriteRune('^')
+	for _, char := range pattern {
+		switch char {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteRune('.')
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	expr.WriteRune('$')
+	return expr.String()
+}
+
+// URLMatcher compares Rave URLs to a pattern
+type URLMatcher struct {
+	Mode    URLMatchMode
+	Pattern string
+	expr    *regexp.Regexp
+}
+
+// create a URLMatcher, checking the pattern is valid for the mode
+func newURLMatcher(mode URLMatchMode, pattern string) (*URLMatcher, error) {
+	matcher := &URLMatcher{Mode: mode, Pattern: pattern}
+	var err error
+	switch mode {
+	case MatchGlob:
+		matcher.expr, err = regexp.Compile(globToRegexp(pattern))
+	case MatchRegex:
+		matcher.expr, err = regexp.Compile(pattern)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return matcher, nil
+}
+
+// does the URL match
+func (m *URLMatcher) matches(url string) bool {
+	switch m.Mode {
+	case MatchExact:
+		return url == m.Pattern
+	case MatchGlob, MatchRegex:
+		return m.expr.MatchString(url)
+	default:
+		return strings.Contains(url, m.Pattern)
+	}
+}
+
+// does either of the URLs for the RaveURL match
+func (m *URLMatcher) matchesRaveURL(raveURL RaveURL) bool {
+	if m.matches(raveURL.PreferredURL) {
+		return true
+	}
+	return raveURL.AlternateURL != "" && m.matches(raveURL.AlternateURL)
+}
+
+// the SQL condition on rave_url and its argument for the matcher
+func (m *URLMatcher) sqlCondition() (string, string) {
+	switch m.Mode {
+	case MatchExact:
+		return `rave_url.url = $1 OR rave_url.alternate_url = $1`, m.Pattern
+	case MatchGlob:
+		return `rave_url.url LIKE $1 ESCAPE '\' OR rave_url.alternate_url LIKE $1 ESCAPE '\'`, globToLike(m.Pattern)
+	case MatchRegex:
+		return `rave_url.url ~ $1 OR rave_url.alternate_url ~ $1`, m.Pattern
+	default:
+		return `rave_url.url LIKE '%' || $1 || '%' ESCAPE '\'
+		OR rave_url.alternate_url LIKE '%' || $1 || '%' ESCAPE '\'`, escapeLike(m.Pattern)
+	}
+}
+
+// match the patterns, dropping the exclusions and any URL matched more than once
+func resolveURLs(ctx context.Context, store Store, patterns, exclusions []*URLMatcher, continueOnError bool) ([]RaveURL, error) {
+	var urls []RaveURL
+	seen := make(map[int]bool)
+	for _, pattern := range patterns {
+		matchingURLs, err := store.GetURLsThatMatch(ctx, pattern)
+		if err != nil {
+			if !continueOnError || ctx.Err() != nil {
+				return nil, fmt.Errorf("matching %s: %w", pattern.Pattern, err)
+			}
+			log.Println("Skipping pattern", pattern.Pattern, ":", err)
+			continue
+		}
+		if len(matchingURLs) == 0 {
+			log.Println("No matching URLs for", pattern.Pattern)
+		}
+	candidates:
+		for _, raveURL := range matchingURLs {
+			if seen[raveURL.URLID] {
+				continue
+			}
+			seen[raveURL.URLID] = true
+			for _, exclusion := range exclusions {
+				if exclusion.matchesRaveURL(raveURL) {
+					log.Println("Excluding", raveURL.URL(), "matching", exclusion.Pattern)
+					continue candidates
+				}
+			}
+			raveURL.MatchedPattern = pattern.Pattern
+			urls = append(urls, raveURL)
+		}
+	}
+	return urls, nil
+}
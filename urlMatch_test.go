@@ -0,0 +1,140 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"pharma", "pharma"},
+		{"pharma_test", `pharma\_test`},
+		{"100%", `100\%`},
+		{`back\slash`, `back\\slash`},
+		{`a_%\b`, `a\_\%\\b`},
+	}
+	for _, test := range tests {
+		if got := escapeLike(test.pattern); got != test.expected {
+			t.Errorf("escapeLike(%q): expected %q, got %q", test.pattern, test.expected, got)
+		}
+	}
+}
+
+func TestGlobToLike(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"pharma*", "pharma%"},
+		{"pharma?.mdsol.com", "pharma_.mdsol.com"},
+		{"SYS?*", "SYS_%"},
+		{"SYS_NC_*", `SYS\_NC\_%`},
+		{"100%*", `100\%%`},
+		{`a\b`, `a\\b`},
+	}
+	for _, test := range tests {
+		if got := globToLike(test.pattern); got != test.expected {
+			t.Errorf("globToLike(%q): expected %q, got %q", test.pattern, test.expected, got)
+		}
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"pharma*", "^pharma.*$"},
+		{"pharma?.mdsol.com", `^pharma.\.mdsol\.com$`},
+		{"a+b(c)", `^a\+b\(c\)$`},
+	}
+	for _, test := range tests {
+		if got := globToRegexp(test.pattern); got != test.expected {
+			t.Errorf("globToRegexp(%q): expected %q, got %q", test.pattern, test.expected, got)
+		}
+	}
+}
+
+func TestURLMatcher(t *testing.T) {
+	tests := []struct {
+		mode     URLMatchMode
+		pattern  string
+		url      string
+		expected bool
+	}{
+		{MatchSubstring, "pharma", "pharma.mdsol.com", true},
+		{MatchSubstring, "pharma", "pharmatest.mdsol.com", true},
+		{MatchSubstring, "pharma_", "pharmatest.mdsol.com", false},
+		{MatchExact, "pharma.mdsol.com", "pharma.mdsol.com", true},
+		{MatchExact, "pharma.mdsol.com", "pharmatest.mdsol.com", false},
+		{MatchExact, "pharma", "pharma.mdsol.com", false},
+		{MatchGlob, "pharma*.mdsol.com", "pharmatest.mdsol.com", true},
+		{MatchGlob, "pharma?.mdsol.com", "pharmatest.mdsol.com", false},
+		{MatchGlob, "pharma.mdsol.com", "pharmaxmdsol.com", false},
+		{MatchGlob, "*.mdsol.com", "pharma.mdsol.com", true},
+		{MatchRegex, `^pharma(test)?\.`, "pharmatest.mdsol.com", true},
+		{MatchRegex, `^pharma\.`, "pharmatest.mdsol.com", false},
+	}
+	for _, test := range tests {
+		matcher, err := newURLMatcher(test.mode, test.pattern)
+		if err != nil {
+			t.Fatalf("newURLMatcher(%s, %q): %v", test.mode, test.pattern, err)
+		}
+		if got := matcher.matches(test.url); got != test.expected {
+			t.Errorf("%s %q matching %q: expected %v, got %v", test.mode, test.pattern, test.url, test.expected, got)
+		}
+	}
+}
+
+func TestURLMatcherInvalid(t *testing.T) {
+	if _, err := newURLMatcher(MatchRegex, "pharma("); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+}
+
+func TestURLMatcherAlternateURL(t *testing.T) {
+	matcher, err := newURLMatcher(MatchExact, "pharma-test.mdsol.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raveURL := RaveURL{PreferredURL: "pharmatest.mdsol.com", AlternateURL: "pharma-test.mdsol.com"}
+	if !matcher.matchesRaveURL(raveURL) {
+		t.Error("expected the alternate URL to match")
+	}
+}
+
+func TestReportJobMatchers(t *testing.T) {
+	job := defaultReportJob()
+	job.Match = MatchGlob.String()
+	job.Patterns = []string{"pharma*"}
+	job.URLs = []string{"pharma", "other.mdsol.com"}
+	job.Exclude = []string{"*test*"}
+	patterns, exclusions, err := job.matchers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []URLMatcher{
+		{Mode: MatchGlob, Pattern: "pharma*"},
+		// the URLs are always exact, qualified with the domain
+		{Mode: MatchExact, Pattern: "pharma.mdsol.com"},
+		{Mode: MatchExact, Pattern: "other.mdsol.com"},
+	}
+	if len(patterns) != len(expected) {
+		t.Fatalf("expected %d patterns, got %d", len(expected), len(patterns))
+	}
+	for idx, matcher := range patterns {
+		if matcher.Mode != expected[idx].Mode || matcher.Pattern != expected[idx].Pattern {
+			t.Errorf("pattern %d: expected %s %q, got %s %q", idx,
+				expected[idx].Mode, expected[idx].Pattern, matcher.Mode, matcher.Pattern)
+		}
+	}
+	if len(exclusions) != 1 || exclusions[0].Mode != MatchGlob {
+		t.Errorf("expected one glob exclusion, got %v", exclusions)
+	}
+	// unlike the substring or glob patterns, the URL only matches its own host
+	if patterns[1].matches("pharmatest.mdsol.com") {
+		t.Error("the -url matcher matched another host")
+	}
+}